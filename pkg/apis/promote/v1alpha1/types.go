@@ -36,6 +36,205 @@ type PromoteSpec struct {
 
 	// KptRule specifies to fetch the apps resource via kpt : https://googlecontainertools.github.io/kpt/
 	KptRule *KptRule `json:"kptRule,omitempty"`
+
+	// Environments specifies the promotion settings of individual environments
+	Environments []EnvironmentSpec `json:"environments,omitempty"`
+
+	// Notifiers specifies where to send notifications of promotion lifecycle events
+	Notifiers []NotifierSpec `json:"notifiers,omitempty"`
+
+	// Hooks specifies commands to run in the clone of the environment repository during the promotion
+	Hooks *Hooks `json:"hooks,omitempty"`
+
+	// PullRequest specifies the go templates of the Pull Requests and commits promoting the applications
+	PullRequest *PullRequestTemplate `json:"pullRequest,omitempty"`
+}
+
+// PullRequestTemplate specifies the go templates of the Pull Requests and commits promoting the applications. The
+// templates can use '{{ .Application }}', '{{ .Version }}', '{{ .PreviousVersion }}', '{{ .Apps }}',
+// '{{ .Environment }}' for the key of the first environment, '{{ .Environments }}', '{{ .Changelog }}',
+// '{{ .BuildURL }}' and '{{ .ReleaseNotesURL }}'
+type PullRequestTemplate struct {
+	// Title the go template of the title of the Pull Request
+	Title string `json:"title,omitempty"`
+
+	// Body the go template of the body of the Pull Request. Any change freeze and approval details are added after
+	// the body along with the changelog if the body does not use '{{ .Changelog }}'
+	Body string `json:"body,omitempty"`
+
+	// CommitMessage the go template of the whole commit message. Defaults to the title and body of the Pull Request
+	CommitMessage string `json:"commitMessage,omitempty"`
+
+	// BranchName the go template of the name of the branch of the Pull Request such as
+	// 'promote/{{ .Environment }}/{{ .Application }}-{{ .Version }}'. The template can use the same expressions as
+	// the title. The branch is also used to find an existing Pull Request to update. Defaults to a generated name
+	BranchName string `json:"branchName,omitempty"`
+}
+
+// Hooks specifies commands to run in the clone of the environment repository during the promotion. If a command
+// fails the promotion is aborted before the Pull Request is created
+type Hooks struct {
+	// PreRule commands to run before the promotion rule modifies the repository
+	PreRule []HookCommand `json:"preRule,omitempty"`
+
+	// PostRule commands to run after the promotion rule has modified the repository such as to regenerate lock files
+	PostRule []HookCommand `json:"postRule,omitempty"`
+
+	// PrePush commands to run after all the changes are made before they are committed and pushed such as
+	// 'helmfile lint'
+	PrePush []HookCommand `json:"prePush,omitempty"`
+}
+
+// HookCommand specifies a command to run. The arguments are go templates which can use the same expressions as the
+// commandTemplate of a fileRule along with '{{ .Application }}', '{{ .Environment }}' and '{{ .Dir }}'
+type HookCommand struct {
+	// Name the name of the command to run
+	Name string `json:"name"`
+
+	// Args the arguments of the command
+	Args []string `json:"args,omitempty"`
+
+	// Dir the directory relative to the root of the repository to run the command in
+	Dir string `json:"dir,omitempty"`
+}
+
+// EnvironmentSpec specifies the promotion settings for an environment
+type EnvironmentSpec struct {
+	// Key the key of the environment in the 'jx-requirements.yml' file
+	Key string `json:"key"`
+
+	// MinSoakTime the minimum time this environment must have been deployed before the next group of
+	// environments is promoted to when using '--all-auto' or '--all'
+	MinSoakTime *metav1.Duration `json:"minSoakTime,omitempty"`
+
+	// VersionConstraint the semantic version constraint versions promoted to this environment must satisfy
+	// such as '~1.4' to only promote patch releases of 1.4
+	VersionConstraint string `json:"versionConstraint,omitempty"`
+
+	// AllowPrerelease specifies whether pre-release versions can be promoted to this environment
+	AllowPrerelease *bool `json:"allowPrerelease,omitempty"`
+
+	// FreezeWindows the change freeze windows during which promotions to this environment are blocked
+	FreezeWindows []FreezeWindow `json:"freezeWindows,omitempty"`
+
+	// RequiredReviewers the user logins or teams in the form 'org/team' whose review is requested on the
+	// Pull Request promoting to this environment
+	RequiredReviewers []string `json:"requiredReviewers,omitempty"`
+
+	// RequiredApprovals the number of approvals the Pull Request needs before it is merged. Defaults to 1 if
+	// there are RequiredReviewers
+	RequiredApprovals int `json:"requiredApprovals,omitempty"`
+
+	// Needs the environments which must be promoted to before this environment. If any environment being promoted
+	// to has needs then the environments are promoted by walking the promotion graph rather than in the order of the
+	// 'jx-requirements.yml' file
+	Needs []EnvironmentNeed `json:"needs,omitempty"`
+
+	// NotificationTemplate the go template of the message of notifications about promoting to this environment.
+	// Overrides the template of the notifiers
+	NotificationTemplate string `json:"notificationTemplate,omitempty"`
+
+	// MergeMethod the method used to merge the Pull Request promoting to this environment: 'merge', 'squash' or
+	// 'rebase'. Overrides the '--merge-method' option
+	MergeMethod string `json:"mergeMethod,omitempty"`
+
+	// MergeCommitTitle the go template of the title of the commit merging the Pull Request promoting to this environment
+	MergeCommitTitle string `json:"mergeCommitTitle,omitempty"`
+
+	// MergeCommitMessage the go template of the body of the commit merging the Pull Request promoting to this
+	// environment. Only supported by GitHub and GitLab
+	MergeCommitMessage string `json:"mergeCommitMessage,omitempty"`
+
+	// RequiredStatusChecks the contexts of the commit statuses and names of the check runs which must succeed before
+	// the Pull Request promoting to this environment is merged. Overrides the '--required-status-check' option.
+	// Defaults to all of the statuses and check runs of the last commit
+	RequiredStatusChecks []string `json:"requiredStatusChecks,omitempty"`
+
+	// PromotionMode how changes are promoted to this environment: 'pullRequest' or 'push'. Defaults to 'pullRequest'.
+	// Promotions to a manual environment, during a change freeze or requiring approvals always use a Pull Request
+	PromotionMode PromotionMode `json:"promotionMode,omitempty"`
+}
+
+// PromotionMode how changes are promoted to an environment
+type PromotionMode string
+
+const (
+	// PromotionModePullRequest promotes via a Pull Request on the environment repository
+	PromotionModePullRequest PromotionMode = "pullRequest"
+
+	// PromotionModePush commits the changes and pushes them directly to the base branch of the environment repository
+	PromotionModePush PromotionMode = "push"
+)
+
+// NeedCondition specifies when the promotion to a needed environment allows the following environment to be
+// promoted to
+type NeedCondition string
+
+const (
+	// NeedConditionSucceeded the promotion to the needed environment must have succeeded
+	NeedConditionSucceeded NeedCondition = "Succeeded"
+
+	// NeedConditionCompleted the promotion to the needed environment must have finished whether it succeeded,
+	// failed or was skipped
+	NeedConditionCompleted NeedCondition = "Completed"
+)
+
+// EnvironmentNeed specifies an edge of the promotion graph from an environment which must be promoted to first
+type EnvironmentNeed struct {
+	// Key the key of the environment in the 'jx-requirements.yml' file which must be promoted to first
+	Key string `json:"key"`
+
+	// Condition when the promotion to the needed environment allows this environment to be promoted to. Either
+	// 'Succeeded' or 'Completed'. Defaults to 'Succeeded'
+	Condition NeedCondition `json:"condition,omitempty"`
+
+	// MinSoakTime the minimum time the needed environment must have been deployed before this environment is
+	// promoted to. Defaults to the minSoakTime of the needed environment
+	MinSoakTime *metav1.Duration `json:"minSoakTime,omitempty"`
+
+	// RequiredReviewers the user logins or teams in the form 'org/team' whose review is requested on the
+	// Pull Request promoting to this environment after the needed environment
+	RequiredReviewers []string `json:"requiredReviewers,omitempty"`
+
+	// RequiredApprovals the number of approvals the Pull Request promoting to this environment after the needed
+	// environment needs before it is merged. Defaults to 1 if there are RequiredReviewers
+	RequiredApprovals int `json:"requiredApprovals,omitempty"`
+}
+
+// FreezeAction specifies what happens to a promotion during a change freeze
+type FreezeAction string
+
+const (
+	// FreezeActionBlock refuses to promote during the freeze window
+	FreezeActionBlock FreezeAction = "block"
+
+	// FreezeActionDraft creates a draft Pull Request which is not merged during the freeze window
+	FreezeActionDraft FreezeAction = "draft"
+)
+
+// FreezeWindow specifies a period of time during which changes to an environment are frozen. Either a date range
+// via Start and End or a recurring window via Schedule and Duration can be specified
+type FreezeWindow struct {
+	// Name the name of the freeze window such as 'month-end' or 'holidays'
+	Name string `json:"name,omitempty"`
+
+	// Start the start of a date range freeze window
+	Start *metav1.Time `json:"start,omitempty"`
+
+	// End the end of a date range freeze window
+	End *metav1.Time `json:"end,omitempty"`
+
+	// Schedule the cron expression for when a recurring freeze window starts such as '0 0 28 * *'
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration how long a recurring freeze window lasts
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// TimeZone the time zone of the Schedule such as 'Europe/London'. Defaults to UTC
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Action what to do when promoting during the freeze window. Either 'block' or 'draft'. Defaults to 'block'
+	Action FreezeAction `json:"action,omitempty"`
 }
 
 // HelmRule specifies which chart to add the app to the Chart's 'requirements.yaml' file
@@ -105,3 +304,42 @@ type PromoteList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []Promote `json:"items"`
 }
+
+// NotifierKind the kind of payload sent to a notifier
+type NotifierKind string
+
+const (
+	// NotifierKindWebhook posts the event and message as JSON to a generic webhook
+	NotifierKindWebhook NotifierKind = "webhook"
+
+	// NotifierKindSlack posts the message to a Slack compatible incoming webhook
+	NotifierKindSlack NotifierKind = "slack"
+
+	// NotifierKindTeams posts the message as a card to a Microsoft Teams incoming webhook
+	NotifierKindTeams NotifierKind = "teams"
+)
+
+// NotifierSpec specifies a sink for notifications of promotion lifecycle events
+type NotifierSpec struct {
+	// Name the name of the notifier used in logs
+	Name string `json:"name,omitempty"`
+
+	// Kind the kind of payload to send. Either 'webhook', 'slack' or 'teams'. Defaults to 'webhook'
+	Kind NotifierKind `json:"kind,omitempty"`
+
+	// URL the URL to post the notifications to
+	URL string `json:"url,omitempty"`
+
+	// URLFromEnv the name of the environment variable containing the URL so that it does not need to be stored in git
+	URLFromEnv string `json:"urlFromEnv,omitempty"`
+
+	// Events the events to notify such as 'PullRequestOpened', 'PullRequestMerged', 'PromotionSucceeded',
+	// 'PromotionFailed' or 'PromotionTimedOut'. Defaults to all events
+	Events []string `json:"events,omitempty"`
+
+	// Environments the keys of the environments to notify about. Defaults to all environments
+	Environments []string `json:"environments,omitempty"`
+
+	// Template the go template of the message
+	Template string `json:"template,omitempty"`
+}
@@ -7,5 +7,9 @@ import (
 
 // Main creates a command object for the command
 func Main() (*cobra.Command, *promote.Options) {
-	return promote.NewCmdPromote()
+	cmd, o := promote.NewCmdPromote()
+	planCmd, _ := promote.NewCmdPromotePlan()
+	cmd.AddCommand(planCmd)
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	return cmd, o
 }
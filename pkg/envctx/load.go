@@ -21,6 +21,15 @@ import (
 )
 
 func (c *EnvironmentContext) LazyLoad(gitClient gitclient.Interface, jxClient versioned.Interface, ns string, gitter gitclient.Interface, dir string) error {
+	err := c.LazyLoadRequirements(gitClient, jxClient, ns, dir)
+	if err != nil {
+		return err
+	}
+	return c.loadVersionResolver(gitter)
+}
+
+// LazyLoadRequirements loads the dev environment and requirements if not already loaded without cloning the version stream
+func (c *EnvironmentContext) LazyLoadRequirements(gitClient gitclient.Interface, jxClient versioned.Interface, ns, dir string) error {
 	err := c.loadDevEnv(jxClient, ns)
 	if err != nil {
 		return err
@@ -31,7 +40,7 @@ func (c *EnvironmentContext) LazyLoad(gitClient gitclient.Interface, jxClient ve
 	}
 	// requirements may override the dev environment git URL used for the version stream
 	c.overrideDevGitURL()
-	return c.loadVersionResolver(gitter)
+	return nil
 }
 
 // overrideDevGitURL overrides the dev environment git URL in place if the
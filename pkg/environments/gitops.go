@@ -7,6 +7,8 @@ import (
 	"sort"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/signing"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/tracing"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/maps"
@@ -15,6 +17,7 @@ import (
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -59,29 +62,9 @@ func (o *EnvironmentPullRequestOptions) Create(gitURL, prDir string, labels []st
 			return nil, fmt.Errorf("failed to ensure repository is forked %s: %w", gitURL, err)
 		}
 	}
-	cloneGitURLSafe := cloneGitURL
-	if o.ScmClientFactory.GitToken != "" && o.ScmClientFactory.GitUsername != "" {
-		cloneGitURL, err = o.ScmClientFactory.CreateAuthenticatedURL(cloneGitURL)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create authenticated git URL to clone with for private repositories: %w", err)
-		}
-	}
-
-	var dir string
-	if len(o.SparseCheckoutPatterns) > 0 {
-		dir, err = gitclient.SparseCloneToDir(o.Gitter, cloneGitURL, "", true, o.SparseCheckoutPatterns...)
-	} else {
-		dir, err = gitclient.CloneToDir(o.Gitter, cloneGitURL, "")
-		if o.BaseBranchName != "" {
-			log.Logger().Infof("checking out remote base branch %s from %s", o.BaseBranchName, gitURL)
-			err = gitclient.CheckoutRemoteBranch(o.Gitter, dir, o.BaseBranchName)
-			if err != nil {
-				return nil, fmt.Errorf("failed to checkout remote branch %s from %s: %w", o.BaseBranchName, gitURL, err)
-			}
-		}
-	}
+	dir, err := o.cloneRepository(gitURL, cloneGitURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to clone git URL %s: %w", cloneGitURLSafe, err)
+		return nil, err
 	}
 
 	if o.Fork {
@@ -92,7 +75,6 @@ func (o *EnvironmentPullRequestOptions) Create(gitURL, prDir string, labels []st
 	}
 
 	o.OutDir = dir
-	log.Logger().Debugf("cloned %s to %s", termcolor.ColorInfo(cloneGitURLSafe), termcolor.ColorInfo(dir))
 
 	currentSha, err := gitclient.GetLatestCommitSha(o.Gitter, dir)
 	if err != nil {
@@ -102,7 +84,9 @@ func (o *EnvironmentPullRequestOptions) Create(gitURL, prDir string, labels []st
 	if o.Function == nil {
 		return nil, fmt.Errorf("no change function configured")
 	}
+	_, span := tracing.Start(o.Context, "rule")
 	err = o.Function()
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to invoke change function in dir %s: %w", dir, err)
 	}
@@ -145,10 +129,99 @@ func (o *EnvironmentPullRequestOptions) Create(gitURL, prDir string, labels []st
 	return prInfo, nil
 }
 
+// cloneRepository clones the git URL, checking out the BaseBranchName if specified, and configures the signing of
+// commits in the clone
+func (o *EnvironmentPullRequestOptions) cloneRepository(gitURL, cloneGitURL string) (string, error) {
+	var err error
+	cloneGitURLSafe := cloneGitURL
+	if o.ScmClientFactory.GitToken != "" && o.ScmClientFactory.GitUsername != "" {
+		cloneGitURL, err = o.ScmClientFactory.CreateAuthenticatedURL(cloneGitURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to create authenticated git URL to clone with for private repositories: %w", err)
+		}
+	}
+
+	_, span := tracing.Start(o.Context, "clone", attribute.String("git.url", cloneGitURLSafe))
+	var dir string
+	if len(o.SparseCheckoutPatterns) > 0 {
+		dir, err = gitclient.SparseCloneToDir(o.Gitter, cloneGitURL, "", true, o.SparseCheckoutPatterns...)
+	} else {
+		dir, err = gitclient.CloneToDir(o.Gitter, cloneGitURL, "")
+		if o.BaseBranchName != "" {
+			log.Logger().Infof("checking out remote base branch %s from %s", o.BaseBranchName, gitURL)
+			err = gitclient.CheckoutRemoteBranch(o.Gitter, dir, o.BaseBranchName)
+			if err != nil {
+				return "", fmt.Errorf("failed to checkout remote branch %s from %s: %w", o.BaseBranchName, gitURL, err)
+			}
+		}
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone git URL %s: %w", cloneGitURLSafe, err)
+	}
+
+	err = signing.Configure(o.Gitter, o.CommandRunner, dir, o.SigningKey, o.SigningFormat)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure the signing of commits in dir %s: %w", dir, err)
+	}
+
+	log.Logger().Debugf("cloned %s to %s", termcolor.ColorInfo(cloneGitURLSafe), termcolor.ColorInfo(dir))
+	return dir, nil
+}
+
+// FindExistingPullRequest finds the open Pull Request of the PullRequestNumber, the open Pull Request matching the
+// labels of the PullRequestFilter or if there is none the open Pull Request of the BranchName
 func (o *EnvironmentPullRequestOptions) FindExistingPullRequest(scmClient *scm.Client, repoFullName string) (*scm.PullRequest, error) {
-	if o.PullRequestFilter == nil {
+	if o.PullRequestNumber > 0 {
+		pr, _, err := scmClient.PullRequests.Find(context.Background(), repoFullName, o.PullRequestNumber)
+		if err != nil && !scmhelpers.IsScmNotFound(err) {
+			return nil, fmt.Errorf("failed to find PullRequest #%d on repo %s: %w", o.PullRequestNumber, repoFullName, err)
+		}
+		if pr != nil && !pr.Closed && !pr.Merged {
+			return pr, nil
+		}
+	}
+	if o.PullRequestFilter != nil {
+		pr, err := o.findLabelledPullRequest(scmClient, repoFullName)
+		if err != nil || pr != nil {
+			return pr, err
+		}
+	}
+	if o.BranchName != "" {
+		return o.findBranchPullRequest(scmClient, repoFullName)
+	}
+	return nil, nil
+}
+
+// findBranchPullRequest finds the open Pull Request from the BranchName so that it can be updated rather than
+// creating another Pull Request from the same branch
+func (o *EnvironmentPullRequestOptions) findBranchPullRequest(scmClient *scm.Client, repoFullName string) (*scm.PullRequest, error) {
+	ctx := context.Background()
+	log.Logger().Debugf("Trying to find open PRs in %s from branch %s", repoFullName, o.BranchName)
+	prs, _, err := scmClient.PullRequests.List(ctx, repoFullName, &scm.PullRequestListOptions{
+		Size: 100,
+		Open: true,
+	})
+	if scmhelpers.IsScmNotFound(err) || len(prs) == 0 {
 		return nil, nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing PRs: %w", err)
+	}
+	for _, pr := range prs {
+		if pr.Closed || pr.Merged || pr.Source != o.BranchName {
+			continue
+		}
+		if pr.Base.Repo.FullName != "" && pr.Base.Repo.FullName != repoFullName {
+			continue
+		}
+		log.Logger().Debugf("Found pr %s from branch %s", pr.Link, o.BranchName)
+		return pr, nil
+	}
+	return nil, nil
+}
+
+func (o *EnvironmentPullRequestOptions) findLabelledPullRequest(scmClient *scm.Client, repoFullName string) (*scm.PullRequest, error) {
 	ctx := context.Background()
 	filterLabels := o.PullRequestFilter.Labels
 	log.Logger().Debugf("Trying to find open PRs in %s with labels %v", repoFullName, filterLabels)
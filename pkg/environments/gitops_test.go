@@ -0,0 +1,59 @@
+//go:build unit
+// +build unit
+
+package environments_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/environments"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindExistingPullRequestByBranch(t *testing.T) {
+	scmClient, fakeData := fake.NewDefault()
+	repoFullName := "myorg/environment-production"
+	repo := scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: repoFullName}
+	pr := func(number int, source string, labels ...string) *scm.PullRequest {
+		answer := &scm.PullRequest{
+			Number: number,
+			Source: source,
+			Base:   scm.PullRequestBranch{Repo: repo},
+		}
+		for _, l := range labels {
+			answer.Labels = append(answer.Labels, &scm.Label{Name: l})
+		}
+		return answer
+	}
+	fakeData.PullRequests[1] = pr(1, "promote/production/myapp-1.2.3")
+	fakeData.PullRequests[2] = pr(2, "some-other-branch", "env/production")
+
+	o := &environments.EnvironmentPullRequestOptions{
+		BranchName: "promote/production/myapp-1.2.3",
+	}
+	existing, err := o.FindExistingPullRequest(scmClient, repoFullName)
+	require.NoError(t, err, "failed to find existing Pull Request")
+	require.NotNil(t, existing, "should have found the Pull Request from the branch")
+	assert.Equal(t, 1, existing.Number, "Pull Request number")
+
+	o.PullRequestFilter = &environments.PullRequestFilter{Labels: []string{"env/production"}}
+	existing, err = o.FindExistingPullRequest(scmClient, repoFullName)
+	require.NoError(t, err, "failed to find existing Pull Request")
+	require.NotNil(t, existing, "should have found the labelled Pull Request")
+	assert.Equal(t, 2, existing.Number, "the labelled Pull Request should be preferred")
+
+	o.PullRequestFilter = nil
+	o.BranchName = "promote/staging/myapp-1.2.3"
+	existing, err = o.FindExistingPullRequest(scmClient, repoFullName)
+	require.NoError(t, err, "failed to find existing Pull Request")
+	assert.Nil(t, existing, "should not find a Pull Request for another branch")
+
+	o.PullRequestNumber = 2
+	existing, err = o.FindExistingPullRequest(scmClient, repoFullName)
+	require.NoError(t, err, "failed to find existing Pull Request")
+	require.NotNil(t, existing, "should have found the Pull Request from its number")
+	assert.Equal(t, 2, existing.Number, "Pull Request number")
+}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/cenkalti/backoff"
 
+	"github.com/jenkins-x-plugins/jx-promote/pkg/scmretry"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/tracing"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
@@ -18,6 +20,7 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/scmhelpers"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Git lazily create a gitter if its not specified
@@ -125,12 +128,18 @@ func (o *EnvironmentPullRequestOptions) CreatePullRequest(scmClient *scm.Client,
 		commitBody += "\n\n" + o.ChangelogSeparator + changelogPrefix + "\n" + o.CommitChangelog
 	}
 	commitMessage := fmt.Sprintf("%s\n\n%s", commitTitle, commitBody)
+	if o.FullCommitMessage != "" {
+		// lets use the whole commit message rather than the title and body of the Pull Request
+		commitMessage = o.FullCommitMessage
+	}
 	_, err = gitclient.AddAndCommitFiles(gitter, dir, strings.TrimSpace(commitMessage))
 	if err != nil {
 		return nil, fmt.Errorf("failed to commit changes in dir %s: %w", dir, err)
 	}
 
+	_, span := tracing.Start(o.Context, "push", attribute.String("git.branch", o.BranchName))
 	err = gitclient.ForcePushBranch(gitter, dir, "HEAD", o.BranchName)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to push to branch %s from dir %s: %w", o.BranchName, dir, err)
 	}
@@ -229,11 +238,13 @@ func (o *EnvironmentPullRequestOptions) addLabelsToPullRequest(ctx context.Conte
 		return nil
 	}
 
+	_, span := tracing.Start(o.Context, "add-labels", attribute.Int("pr.number", prNumber))
 	bo := backoff.NewExponentialBackOff()
 	bo.InitialInterval = 3 * time.Second
 	bo.MaxElapsedTime = time.Minute
 	bo.Reset()
 	err = backoff.Retry(f, bo)
+	tracing.End(span, err)
 	if err != nil {
 		return pr, err
 	}
@@ -262,5 +273,6 @@ func (o *EnvironmentPullRequestOptions) CreateScmClient(gitServer, _, gitKind st
 	if err != nil {
 		return scmClient, "", fmt.Errorf("failed to create SCM client for server %s: %w", gitServer, err)
 	}
+	scmretry.Wrap(scmClient)
 	return scmClient, o.ScmClientFactory.GitToken, nil
 }
@@ -0,0 +1,118 @@
+package environments
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/tracing"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxPushAttempts the maximum number of times the change function is applied to a fresh clone when pushing directly
+// to the base branch is rejected as it is not a fast forward
+const MaxPushAttempts = 3
+
+// Push clones the git URL, invokes the change function and commits and pushes the changes directly to the base branch
+// without a Pull Request. If the push is rejected because the base branch has moved on the repository is cloned again
+// and the change function re-applied. Returns the SHA of the pushed commit or an empty string if there were no changes
+func (o *EnvironmentPullRequestOptions) Push(gitURL string) (string, error) {
+	if gitURL == "" {
+		log.Logger().Infof("no git URL specified so cannot push the promotion")
+		return "", nil
+	}
+	if o.Function == nil {
+		return "", fmt.Errorf("no change function configured")
+	}
+	_, _, err := o.GetScmClient(gitURL, o.GitKind)
+	if err != nil {
+		return "", fmt.Errorf("failed to create ScmClient: %w", err)
+	}
+	o.Git()
+
+	for attempt := 1; ; attempt++ {
+		sha, err := o.pushChanges(gitURL)
+		if err == nil || !IsNonFastForward(err) {
+			return sha, err
+		}
+		if attempt >= MaxPushAttempts {
+			return "", fmt.Errorf("failed to push to %s after %d attempts: %w", gitURL, attempt, err)
+		}
+		log.Logger().Infof("the push to %s was rejected as the branch has changed so re-applying the promotion to a fresh clone", termcolor.ColorInfo(gitURL))
+	}
+}
+
+// pushChanges clones the repository, invokes the change function and pushes any changes to the base branch
+func (o *EnvironmentPullRequestOptions) pushChanges(gitURL string) (string, error) {
+	gitter := o.Gitter
+	dir, err := o.cloneRepository(gitURL, gitURL)
+	if err != nil {
+		return "", err
+	}
+	o.OutDir = dir
+
+	branch := o.BaseBranchName
+	if branch == "" {
+		branch, err = gitclient.Branch(gitter, dir)
+		if err != nil {
+			return "", fmt.Errorf("failed to find branch in dir %s: %w", dir, err)
+		}
+	}
+
+	_, span := tracing.Start(o.Context, "rule")
+	err = o.Function()
+	tracing.End(span, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to invoke change function in dir %s: %w", dir, err)
+	}
+
+	changes, err := gitclient.HasChanges(gitter, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect if there were git changes in dir %s: %w", dir, err)
+	}
+	if !changes {
+		log.Logger().Infof("no changes detected so not pushing to %s", termcolor.ColorInfo(gitURL))
+		return "", nil
+	}
+
+	_, _, err = gitclient.EnsureUserAndEmailSetup(gitter, dir, "", "")
+	if err != nil {
+		return "", fmt.Errorf("failed to setup git user and email: %w", err)
+	}
+	commitMessage := o.FullCommitMessage
+	if commitMessage == "" {
+		commitMessage = strings.TrimSpace(o.CommitTitle) + "\n\n" + o.CommitMessage
+		if o.CommitChangelog != "" {
+			commitMessage += "\n\n" + o.CommitChangelog
+		}
+	}
+	_, err = gitclient.AddAndCommitFiles(gitter, dir, strings.TrimSpace(commitMessage))
+	if err != nil {
+		return "", fmt.Errorf("failed to commit changes in dir %s: %w", dir, err)
+	}
+
+	_, span = tracing.Start(o.Context, "push", attribute.String("git.branch", branch))
+	_, err = gitter.Command(dir, "push", "origin", "HEAD:"+branch)
+	tracing.End(span, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to push to branch %s from dir %s: %w", branch, dir, err)
+	}
+
+	sha, err := gitclient.GetLatestCommitSha(gitter, dir)
+	if err != nil {
+		return "", fmt.Errorf("could not get the pushed commit sha: %w", err)
+	}
+	log.Logger().Infof("pushed commit %s to branch %s of %s", termcolor.ColorInfo(sha), termcolor.ColorInfo(branch), termcolor.ColorInfo(gitURL))
+	return sha, nil
+}
+
+// IsNonFastForward returns true if the error is from a push rejected as it is not a fast forward of the remote branch
+func IsNonFastForward(err error) bool {
+	if err == nil {
+		return false
+	}
+	text := err.Error()
+	return strings.Contains(text, "non-fast-forward") || strings.Contains(text, "fetch first") || strings.Contains(text, "[rejected]")
+}
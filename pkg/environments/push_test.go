@@ -0,0 +1,25 @@
+//go:build unit
+// +build unit
+
+package environments_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/environments"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNonFastForward(t *testing.T) {
+	rejected := errors.New(` ! [rejected]        HEAD -> main (fetch first)
+error: failed to push some refs to 'https://github.com/myorg/environment-production.git'`)
+	nonFastForward := errors.New(` ! [rejected]        HEAD -> main (non-fast-forward)`)
+
+	assert.True(t, environments.IsNonFastForward(rejected), "fetch first")
+	assert.True(t, environments.IsNonFastForward(nonFastForward), "non-fast-forward")
+	assert.True(t, environments.IsNonFastForward(fmt.Errorf("failed to push: %w", nonFastForward)), "wrapped")
+	assert.False(t, environments.IsNonFastForward(errors.New("remote: Permission denied")), "permission denied")
+	assert.False(t, environments.IsNonFastForward(nil), "no error")
+}
@@ -1,6 +1,8 @@
 package environments
 
 import (
+	"context"
+
 	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
 	"github.com/jenkins-x-plugins/jx-promote/pkg/envctx"
 	"github.com/jenkins-x/go-scm/scm"
@@ -32,7 +34,9 @@ type EnvironmentPullRequestOptions struct {
 	// PullRequestFilter used to find an existing Pull Request to rebase/modify
 	PullRequestFilter *PullRequestFilter
 
-	DevEnvContext    envctx.EnvironmentContext
+	DevEnvContext envctx.EnvironmentContext
+	// Context the parent context of the tracing spans
+	Context          context.Context
 	ScmClientFactory scmhelpers.Factory
 	Gitter           gitclient.Interface
 	CommandRunner    cmdrunner.CommandRunner
@@ -49,6 +53,7 @@ type EnvironmentPullRequestOptions struct {
 	BranchName             string
 	CommitTitle            string
 	CommitMessage          string
+	FullCommitMessage      string
 	CommitChangelog        string
 	ChangelogSeparator     string
 	Namespace              string
@@ -60,6 +65,10 @@ type EnvironmentPullRequestOptions struct {
 	ReusePullRequest       bool
 	SparseCheckoutPatterns []string
 	Application            string
+	// SigningKey the path or contents of the key used to sign commits
+	SigningKey string
+	// SigningFormat the format of the commit signatures: ssh or openpgp
+	SigningFormat string
 }
 
 // A PullRequestFilter defines a filter for finding pull requests
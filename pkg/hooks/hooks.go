@@ -0,0 +1,97 @@
+package hooks
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/rules"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// PreRule the hook run before the promotion rule
+	PreRule = "preRule"
+
+	// PostRule the hook run after the promotion rule
+	PostRule = "postRule"
+
+	// PrePush the hook run before the changes are committed and pushed
+	PrePush = "prePush"
+)
+
+// TemplateContext expressions used in the arguments of hook commands
+type TemplateContext struct {
+	rules.TemplateContext
+
+	// Application the name of the application being promoted. The same as AppName but named like the Pull Request
+	// templates
+	Application string
+
+	// Environment the key of the environment being promoted to
+	Environment string
+
+	// Dir the directory of the clone of the environment repository
+	Dir string
+}
+
+// Run runs the commands of the hook in the clone directory of the context evaluating their arguments as go templates
+func Run(runner cmdrunner.CommandRunner, hook string, commands []v1alpha1.HookCommand, ctx *TemplateContext) error {
+	if runner == nil {
+		runner = cmdrunner.DefaultCommandRunner
+	}
+	for i := range commands {
+		c := &commands[i]
+		if c.Name == "" {
+			return fmt.Errorf("no name for %s hook command %d", hook, i+1)
+		}
+		dir := ctx.Dir
+		if c.Dir != "" {
+			if !filepath.IsLocal(c.Dir) {
+				return fmt.Errorf("dir %s of %s hook command %s must be a relative path inside the repository", c.Dir, hook, c.Name)
+			}
+			dir = filepath.Join(dir, c.Dir)
+		}
+		cmd := &cmdrunner.Command{
+			Dir:  dir,
+			Name: c.Name,
+		}
+		for _, arg := range c.Args {
+			value, err := evaluateTemplate(arg, ctx)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate argument of %s hook command %s: %w", hook, c.Name, err)
+			}
+			cmd.Args = append(cmd.Args, value)
+		}
+
+		log.Logger().Infof("running %s hook %s", hook, termcolor.ColorInfo(cmdrunner.CLI(cmd)))
+		output, err := runner(cmd)
+		if err != nil {
+			if output != "" && !strings.Contains(err.Error(), output) {
+				return fmt.Errorf("%s hook %s failed with output: %s: %w", hook, cmdrunner.CLI(cmd), output, err)
+			}
+			return fmt.Errorf("%s hook %s failed: %w", hook, cmdrunner.CLI(cmd), err)
+		}
+	}
+	return nil
+}
+
+func evaluateTemplate(templateText string, ctx *TemplateContext) (string, error) {
+	if !strings.Contains(templateText, "{{") {
+		return templateText, nil
+	}
+	tmpl, err := template.New("arg").Option("missingkey=error").Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse go template: %s: %w", templateText, err)
+	}
+	buf := &strings.Builder{}
+	err = tmpl.Execute(buf, ctx)
+	if err != nil {
+		return buf.String(), fmt.Errorf("failed to evaluate template %s: %w", templateText, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,79 @@
+//go:build unit
+// +build unit
+
+package hooks_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/hooks"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/rules"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	runner := &fakerunner.FakeRunner{}
+	ctx := &hooks.TemplateContext{
+		TemplateContext: rules.TemplateContext{
+			AppName: "myapp",
+			Version: "1.2.3",
+		},
+		Application: "myapp",
+		Environment: "staging",
+		Dir:         dir,
+	}
+	err := hooks.Run(runner.Run, hooks.PostRule, []v1alpha1.HookCommand{
+		{
+			Name: "helmfile",
+			Args: []string{"--file", "helmfiles/{{ .Environment }}/helmfile.yaml", "deps"},
+		},
+		{
+			Name: "make",
+			Args: []string{"lock", "APP={{ .Application }}", "VERSION={{ .Version }}"},
+			Dir:  "charts",
+		},
+	}, ctx)
+	require.NoError(t, err, "failed to run hooks")
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{
+			CLI: "helmfile --file helmfiles/staging/helmfile.yaml deps",
+			Dir: dir,
+		},
+		fakerunner.FakeResult{
+			CLI: "make lock APP=myapp VERSION=1.2.3",
+			Dir: filepath.Join(dir, "charts"),
+		},
+	)
+}
+
+func TestRunFailure(t *testing.T) {
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			return "helmfile.yaml: line 3: mapping values are not allowed", errors.New("exit status 1")
+		},
+	}
+	err := hooks.Run(runner.Run, hooks.PrePush, []v1alpha1.HookCommand{
+		{
+			Name: "helmfile",
+			Args: []string{"lint"},
+		},
+		{
+			Name: "never",
+		},
+	}, &hooks.TemplateContext{Dir: t.TempDir()})
+	require.Error(t, err, "hook should fail")
+	assert.Contains(t, err.Error(), "prePush hook helmfile lint failed", "error")
+	assert.Contains(t, err.Error(), "mapping values are not allowed", "error should contain the output")
+	assert.Len(t, runner.OrderedCommands, 1, "should stop at the first failing command")
+
+	err = hooks.Run(runner.Run, hooks.PreRule, []v1alpha1.HookCommand{{Name: "ls", Dir: "../.."}}, &hooks.TemplateContext{Dir: t.TempDir()})
+	require.Error(t, err, "should not run outside of the repository")
+}
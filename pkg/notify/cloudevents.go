@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CloudEventsSpecVersion the version of the CloudEvents specification of the events
+	CloudEventsSpecVersion = "1.0"
+
+	// CloudEventsContentType the content type of CloudEvents in the structured content mode
+	CloudEventsContentType = "application/cloudevents+json"
+
+	// CloudEventTypePrefix the prefix of the types of the CloudEvents of the promotion lifecycle
+	CloudEventTypePrefix = "dev.jenkins-x."
+
+	// DefaultEventSource the default source of the CloudEvents
+	DefaultEventSource = "/jenkins-x/jx-promote"
+
+	// CDEventsSpecVersion the version of the CDEvents specification of the events
+	CDEventsSpecVersion = "0.4.1"
+)
+
+// CloudEvent a CloudEvent in the structured content mode
+type CloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// CDEvent an event using the vocabulary of the CDEvents specification
+type CDEvent struct {
+	Context               CDEventContext `json:"context"`
+	Subject               CDEventSubject `json:"subject"`
+	CustomData            interface{}    `json:"customData,omitempty"`
+	CustomDataContentType string         `json:"customDataContentType,omitempty"`
+}
+
+// CDEventContext the context of a CDEvent
+type CDEventContext struct {
+	Version   string    `json:"version"`
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CDEventSubject the subject of a CDEvent
+type CDEventSubject struct {
+	ID      string                 `json:"id"`
+	Source  string                 `json:"source,omitempty"`
+	Content map[string]interface{} `json:"content"`
+}
+
+// CloudEventSink sends promotion lifecycle events as CloudEvents over HTTP
+type CloudEventSink struct {
+	// URL the URL of the sink
+	URL string
+
+	// Source the source of the events
+	Source string
+
+	// CDEvents if enabled the events use the vocabulary of the CDEvents specification
+	CDEvents bool
+
+	// HTTPClient the client used to post the events
+	HTTPClient *http.Client
+}
+
+// CloudEventType returns the type of the CloudEvent of the promotion lifecycle event. A promotion timing out is
+// a failed promotion
+func CloudEventType(t EventType) string {
+	switch t {
+	case EventPromotionStarted:
+		return CloudEventTypePrefix + "promotion.started"
+	case EventPullRequestOpened:
+		return CloudEventTypePrefix + "pullrequest.created"
+	case EventPullRequestMerged:
+		return CloudEventTypePrefix + "pullrequest.merged"
+	case EventPromotionSucceeded:
+		return CloudEventTypePrefix + "promotion.succeeded"
+	default:
+		return CloudEventTypePrefix + "promotion.failed"
+	}
+}
+
+// CDEventType returns the type of the CDEvent of the promotion lifecycle event
+func CDEventType(t EventType) string {
+	switch t {
+	case EventPromotionStarted:
+		return "dev.cdevents.pipelinerun.started.0.2.0"
+	case EventPullRequestOpened:
+		return "dev.cdevents.change.created.0.2.0"
+	case EventPullRequestMerged:
+		return "dev.cdevents.change.merged.0.2.0"
+	case EventPromotionSucceeded:
+		return "dev.cdevents.service.upgraded.0.2.0"
+	default:
+		return "dev.cdevents.pipelinerun.finished.0.2.0"
+	}
+}
+
+// Send posts the event to the sink
+func (s *CloudEventSink) Send(ctx context.Context, e *Event) error {
+	ce := s.CloudEvent(e)
+	payload, err := json.Marshal(ce)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CloudEvent %s: %w", ce.Type, err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", CloudEventsContentType)
+	err = post(ctx, s.HTTPClient, s.URL, header, payload)
+	if err != nil {
+		return fmt.Errorf("failed to send CloudEvent %s: %w", ce.Type, err)
+	}
+	return nil
+}
+
+// CloudEvent returns the CloudEvent of the promotion lifecycle event
+func (s *CloudEventSink) CloudEvent(e *Event) *CloudEvent {
+	source := s.Source
+	if source == "" {
+		source = DefaultEventSource
+	}
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	ce := &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              uuid.New().String(),
+		Source:          source,
+		Type:            CloudEventType(e.Type),
+		Subject:         e.Environment,
+		Time:            t.UTC(),
+		DataContentType: "application/json",
+		Data:            e,
+	}
+	if s.CDEvents {
+		ce.Type = CDEventType(e.Type)
+		ce.Data = NewCDEvent(ce, e)
+	}
+	return ce
+}
+
+// NewCDEvent returns the CDEvent of the promotion lifecycle event with the context of the CloudEvent
+func NewCDEvent(ce *CloudEvent, e *Event) *CDEvent {
+	answer := &CDEvent{
+		Context: CDEventContext{
+			Version:   CDEventsSpecVersion,
+			ID:        ce.ID,
+			Source:    ce.Source,
+			Type:      ce.Type,
+			Timestamp: ce.Time,
+		},
+		Subject: CDEventSubject{
+			Source:  ce.Source,
+			Content: map[string]interface{}{},
+		},
+		CustomData:            e,
+		CustomDataContentType: "application/json",
+	}
+	subject := &answer.Subject
+	switch e.Type {
+	case EventPullRequestOpened, EventPullRequestMerged:
+		subject.ID = e.PullRequestURL
+		if repo := pullRequestRepository(e.PullRequestURL); repo != "" {
+			subject.Content["repository"] = map[string]string{"id": repo}
+		}
+	case EventPromotionSucceeded:
+		subject.ID = e.Environment + "/" + e.Application
+		subject.Content["environment"] = map[string]string{"id": e.Environment}
+		subject.Content["artifactId"] = "pkg:generic/" + e.Application + "@" + e.Version
+	default:
+		subject.ID = e.PipelineActivity
+		if subject.ID == "" {
+			subject.ID = e.Application
+		}
+		subject.Content["pipelineName"] = e.PipelineActivity
+		if e.Type != EventPromotionStarted {
+			subject.Content["outcome"] = "failure"
+			subject.Content["errors"] = e.Error
+		}
+	}
+	return answer
+}
+
+// pullRequestRepository returns the URL of the repository of the Pull Request URL
+func pullRequestRepository(prURL string) string {
+	for _, sep := range []string{"/pull/", "/pulls/", "/merge_requests/", "/pull-requests/"} {
+		i := strings.LastIndex(prURL, sep)
+		if i > 0 {
+			return strings.TrimSuffix(strings.TrimSuffix(prURL[:i], "/-"), "/")
+		}
+	}
+	return ""
+}
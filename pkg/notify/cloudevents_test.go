@@ -0,0 +1,86 @@
+//go:build unit
+// +build unit
+
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEventSink(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, notify.CloudEventsContentType, r.Header.Get("Content-Type"), "content type")
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read body")
+		body := map[string]interface{}{}
+		err = json.Unmarshal(data, &body)
+		require.NoError(t, err, "failed to unmarshal body %s", string(data))
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	e := &notify.Event{
+		Type:             notify.EventPullRequestOpened,
+		Application:      "myapp",
+		Version:          "1.2.3",
+		Environment:      "staging",
+		PullRequestURL:   "https://github.com/myorg/environment-staging/pull/5",
+		PipelineActivity: "myorg-myapp-master-7",
+	}
+
+	sink := &notify.CloudEventSink{URL: server.URL}
+	err := sink.Send(context.TODO(), e)
+	require.NoError(t, err, "failed to send CloudEvent")
+
+	sink.CDEvents = true
+	err = sink.Send(context.TODO(), e)
+	require.NoError(t, err, "failed to send CDEvent")
+	require.Len(t, bodies, 2, "posted bodies")
+
+	ce := bodies[0]
+	assert.Equal(t, "1.0", ce["specversion"], "specversion")
+	assert.Equal(t, "dev.jenkins-x.pullrequest.created", ce["type"], "type")
+	assert.Equal(t, notify.DefaultEventSource, ce["source"], "source")
+	assert.Equal(t, "staging", ce["subject"], "subject")
+	assert.NotEmpty(t, ce["id"], "id")
+	data := ce["data"].(map[string]interface{})
+	assert.Equal(t, "myapp", data["application"], "data application")
+	assert.Equal(t, "1.2.3", data["version"], "data version")
+	assert.Equal(t, "myorg-myapp-master-7", data["pipelineActivity"], "data pipelineActivity")
+	assert.Equal(t, e.PullRequestURL, data["pullRequestURL"], "data pullRequestURL")
+
+	cd := bodies[1]
+	assert.Equal(t, "dev.cdevents.change.created.0.2.0", cd["type"], "CDEvent type")
+	cdData := cd["data"].(map[string]interface{})
+	cdContext := cdData["context"].(map[string]interface{})
+	assert.Equal(t, cd["id"], cdContext["id"], "CDEvent context id")
+	assert.Equal(t, cd["type"], cdContext["type"], "CDEvent context type")
+	subject := cdData["subject"].(map[string]interface{})
+	assert.Equal(t, e.PullRequestURL, subject["id"], "CDEvent subject id")
+	assert.Equal(t, map[string]interface{}{"id": "https://github.com/myorg/environment-staging"}, subject["content"].(map[string]interface{})["repository"], "CDEvent repository")
+}
+
+func TestCloudEventType(t *testing.T) {
+	assert.Equal(t, "dev.jenkins-x.promotion.started", notify.CloudEventType(notify.EventPromotionStarted))
+	assert.Equal(t, "dev.jenkins-x.pullrequest.merged", notify.CloudEventType(notify.EventPullRequestMerged))
+	assert.Equal(t, "dev.jenkins-x.promotion.succeeded", notify.CloudEventType(notify.EventPromotionSucceeded))
+	assert.Equal(t, "dev.jenkins-x.promotion.failed", notify.CloudEventType(notify.EventPromotionTimedOut))
+
+	sink := &notify.CloudEventSink{CDEvents: true}
+	ce := sink.CloudEvent(&notify.Event{Type: notify.EventPromotionSucceeded, Application: "myapp", Version: "1.2.3", Environment: "production"})
+	cd := ce.Data.(*notify.CDEvent)
+	assert.Equal(t, "dev.cdevents.service.upgraded.0.2.0", ce.Type, "type")
+	assert.Equal(t, "production/myapp", cd.Subject.ID, "subject id")
+	assert.Equal(t, "pkg:generic/myapp@1.2.3", cd.Subject.Content["artifactId"], "artifactId")
+}
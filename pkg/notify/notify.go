@@ -0,0 +1,313 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+)
+
+// EventType the type of a promotion lifecycle event
+type EventType string
+
+const (
+	// EventPromotionStarted the promotion to the environments started
+	EventPromotionStarted EventType = "PromotionStarted"
+
+	// EventPullRequestOpened the Pull Request promoting to the environments was created
+	EventPullRequestOpened EventType = "PullRequestOpened"
+
+	// EventPullRequestMerged the Pull Request promoting to the environments was merged
+	EventPullRequestMerged EventType = "PullRequestMerged"
+
+	// EventPromotionSucceeded the promotion to the environments completed
+	EventPromotionSucceeded EventType = "PromotionSucceeded"
+
+	// EventPromotionFailed the promotion to the environments failed
+	EventPromotionFailed EventType = "PromotionFailed"
+
+	// EventPromotionTimedOut the promotion to the environments did not complete before the timeout
+	EventPromotionTimedOut EventType = "PromotionTimedOut"
+
+	// DefaultTemplate the default go template of the message of a notification
+	DefaultTemplate = `{{ .Application }}{{ if .Version }} version {{ .Version }}{{ end }}: {{ .Summary }} for environment {{ .Environment }}` +
+		`{{ if .PullRequestURL }} {{ .PullRequestURL }}{{ end }}{{ if .Error }}: {{ .Error }}{{ end }}`
+)
+
+// EventTypes the types of all the promotion lifecycle events
+var EventTypes = []EventType{
+	EventPromotionStarted,
+	EventPullRequestOpened,
+	EventPullRequestMerged,
+	EventPromotionSucceeded,
+	EventPromotionFailed,
+	EventPromotionTimedOut,
+}
+
+// Event a promotion lifecycle event
+type Event struct {
+	// Type the type of the event
+	Type EventType `json:"type"`
+
+	// Application the name of the application being promoted
+	Application string `json:"application"`
+
+	// Version the version being promoted
+	Version string `json:"version,omitempty"`
+
+	// Environment the key of the first environment being promoted to
+	Environment string `json:"environment"`
+
+	// Environments the keys of all the environments promoted to via the Pull Request
+	Environments []string `json:"environments,omitempty"`
+
+	// PullRequestURL the URL of the Pull Request
+	PullRequestURL string `json:"pullRequestURL,omitempty"`
+
+	// PullRequestNumber the number of the Pull Request
+	PullRequestNumber int `json:"pullRequestNumber,omitempty"`
+
+	// ApplicationURL the URL the application is available at once promoted
+	ApplicationURL string `json:"applicationURL,omitempty"`
+
+	// PipelineActivity the name of the PipelineActivity recording the promotion
+	PipelineActivity string `json:"pipelineActivity,omitempty"`
+
+	// Error the error message if the promotion failed
+	Error string `json:"error,omitempty"`
+
+	// Time when the event happened
+	Time time.Time `json:"time"`
+}
+
+// Summary returns a short description of the event
+func (e *Event) Summary() string {
+	switch e.Type {
+	case EventPromotionStarted:
+		return "promotion started"
+	case EventPullRequestOpened:
+		return "promotion Pull Request opened"
+	case EventPullRequestMerged:
+		return "promotion Pull Request merged"
+	case EventPromotionSucceeded:
+		return "promotion succeeded"
+	case EventPromotionFailed:
+		return "promotion failed"
+	case EventPromotionTimedOut:
+		return "promotion timed out"
+	default:
+		return string(e.Type)
+	}
+}
+
+// Notifier sends notifications of promotion lifecycle events to a sink
+type Notifier struct {
+	// Name the name of the notifier used in logs
+	Name string
+
+	// Kind the kind of payload to send
+	Kind v1alpha1.NotifierKind
+
+	// URL the URL to post to
+	URL string
+
+	// Events the events to notify or empty for all events other than PromotionStarted
+	Events []string
+
+	// Environments the keys of the environments to notify about or empty for all environments
+	Environments []string
+
+	// Template the go template of the message
+	Template string
+
+	// HTTPClient the client used to post the notifications
+	HTTPClient *http.Client
+}
+
+// NewNotifiers creates the notifiers from their configuration
+func NewNotifiers(specs []v1alpha1.NotifierSpec) ([]*Notifier, error) {
+	var answer []*Notifier
+	for i := range specs {
+		spec := &specs[i]
+		n := &Notifier{
+			Name:         spec.Name,
+			Kind:         spec.Kind,
+			URL:          spec.URL,
+			Events:       spec.Events,
+			Environments: spec.Environments,
+			Template:     spec.Template,
+		}
+		if n.Kind == "" {
+			n.Kind = v1alpha1.NotifierKindWebhook
+		}
+		switch n.Kind {
+		case v1alpha1.NotifierKindWebhook, v1alpha1.NotifierKindSlack, v1alpha1.NotifierKindTeams:
+		default:
+			return nil, fmt.Errorf("invalid kind %s of notifier %s: expected %s, %s or %s", n.Kind, n.String(), v1alpha1.NotifierKindWebhook, v1alpha1.NotifierKindSlack, v1alpha1.NotifierKindTeams)
+		}
+		if n.URL == "" && spec.URLFromEnv != "" {
+			n.URL = os.Getenv(spec.URLFromEnv)
+			if n.URL == "" {
+				return nil, fmt.Errorf("no $%s environment variable defined for the URL of notifier %s", spec.URLFromEnv, n.String())
+			}
+		}
+		if n.URL == "" {
+			return nil, fmt.Errorf("no url for notifier %s", n.String())
+		}
+		for _, e := range n.Events {
+			if !isEventType(e) {
+				return nil, fmt.Errorf("invalid event %s of notifier %s", e, n.String())
+			}
+		}
+		answer = append(answer, n)
+	}
+	return answer, nil
+}
+
+func isEventType(text string) bool {
+	for _, t := range EventTypes {
+		if string(t) == text {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the name of the notifier or its kind if it has no name
+func (n *Notifier) String() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return string(n.Kind)
+}
+
+// Matches returns true if the notifier should be notified of the event
+func (n *Notifier) Matches(e *Event) bool {
+	if len(n.Events) == 0 {
+		if e.Type == EventPromotionStarted {
+			return false
+		}
+	} else if stringhelpers.StringArrayIndex(n.Events, string(e.Type)) < 0 {
+		return false
+	}
+	if len(n.Environments) == 0 {
+		return true
+	}
+	for _, env := range e.Environments {
+		if stringhelpers.StringArrayIndex(n.Environments, env) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify posts the event to the notifier. The template overrides the template of the notifier if specified
+func (n *Notifier) Notify(ctx context.Context, e *Event, templateText string) error {
+	if templateText == "" {
+		templateText = n.Template
+	}
+	message, err := Message(templateText, e)
+	if err != nil {
+		return err
+	}
+	payload, err := n.payload(e, message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the payload of notifier %s: %w", n.String(), err)
+	}
+
+	err = post(ctx, n.HTTPClient, n.URL, nil, payload)
+	if err != nil {
+		return fmt.Errorf("failed to notify %s: %w", n.String(), err)
+	}
+	return nil
+}
+
+// post posts the JSON payload to the URL failing if the response is not successful
+func post(ctx context.Context, httpClient *http.Client, u string, header http.Header, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, values := range header {
+		req.Header[k] = values
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", u, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// payload returns the JSON body to post for the kind of the notifier
+func (n *Notifier) payload(e *Event, message string) ([]byte, error) {
+	switch n.Kind {
+	case v1alpha1.NotifierKindSlack:
+		return json.Marshal(map[string]string{
+			"text": message,
+		})
+	case v1alpha1.NotifierKindTeams:
+		return json.Marshal(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    e.Summary(),
+			"themeColor": themeColor(e.Type),
+			"title":      e.Application + " " + e.Summary(),
+			"text":       message,
+		})
+	default:
+		return json.Marshal(&struct {
+			*Event
+			Message string `json:"message"`
+		}{
+			Event:   e,
+			Message: message,
+		})
+	}
+}
+
+// themeColor returns the color of the Microsoft Teams card of the event
+func themeColor(t EventType) string {
+	switch t {
+	case EventPromotionFailed, EventPromotionTimedOut:
+		return "D70000"
+	case EventPullRequestMerged, EventPromotionSucceeded:
+		return "2DC72D"
+	default:
+		return "0076D7"
+	}
+}
+
+// Message evaluates the go template of the message of the event using the DefaultTemplate if none is specified
+func Message(templateText string, e *Event) (string, error) {
+	if templateText == "" {
+		templateText = DefaultTemplate
+	}
+	tmpl, err := template.New("message").Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse go template: %s: %w", templateText, err)
+	}
+	buf := &strings.Builder{}
+	err = tmpl.Execute(buf, e)
+	if err != nil {
+		return buf.String(), fmt.Errorf("failed to evaluate template with %#v: %w", e, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,134 @@
+//go:build unit
+// +build unit
+
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method, "method")
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"), "content type")
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read body")
+		body := map[string]interface{}{}
+		err = json.Unmarshal(data, &body)
+		require.NoError(t, err, "failed to unmarshal body %s", string(data))
+		bodies = append(bodies, body)
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_NOTIFY_URL", server.URL)
+	notifiers, err := notify.NewNotifiers([]v1alpha1.NotifierSpec{
+		{
+			URL: server.URL,
+		},
+		{
+			Kind:       v1alpha1.NotifierKindSlack,
+			URLFromEnv: "TEST_NOTIFY_URL",
+			Template:   "{{ .Application }} {{ .Version }} is in {{ .Environment }}",
+		},
+		{
+			Kind: v1alpha1.NotifierKindTeams,
+			URL:  server.URL,
+		},
+	})
+	require.NoError(t, err, "failed to create notifiers")
+	require.Len(t, notifiers, 3, "notifiers")
+
+	e := &notify.Event{
+		Type:           notify.EventPullRequestMerged,
+		Application:    "myapp",
+		Version:        "1.2.3",
+		Environment:    "staging",
+		Environments:   []string{"staging"},
+		PullRequestURL: "https://github.com/myorg/environment-staging/pull/5",
+	}
+	for _, n := range notifiers {
+		err = n.Notify(context.TODO(), e, "")
+		require.NoError(t, err, "failed to notify %s", n.String())
+	}
+	require.Len(t, bodies, 3, "posted bodies")
+
+	message := "myapp version 1.2.3: promotion Pull Request merged for environment staging https://github.com/myorg/environment-staging/pull/5"
+	assert.Equal(t, "PullRequestMerged", bodies[0]["type"], "webhook type")
+	assert.Equal(t, "myapp", bodies[0]["application"], "webhook application")
+	assert.NotContains(t, bodies[0], "error", "webhook error")
+	assert.Equal(t, message, bodies[0]["message"], "webhook message")
+	assert.Equal(t, "myapp 1.2.3 is in staging", bodies[1]["text"], "slack text")
+	assert.Equal(t, "MessageCard", bodies[2]["@type"], "teams type")
+	assert.Equal(t, message, bodies[2]["text"], "teams text")
+
+	err = notifiers[1].Notify(context.TODO(), e, "deployed {{ .Application }}")
+	require.NoError(t, err, "failed to notify with an environment template")
+	assert.Equal(t, "deployed myapp", bodies[3]["text"], "environment template should override the notifier template")
+}
+
+func TestNotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such channel", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	n := &notify.Notifier{Kind: v1alpha1.NotifierKindSlack, URL: server.URL}
+	err := n.Notify(context.TODO(), &notify.Event{Type: notify.EventPromotionFailed, Application: "myapp"}, "")
+	require.Error(t, err, "should fail on a 404")
+	assert.Contains(t, err.Error(), "no such channel", "error")
+}
+
+func TestMatches(t *testing.T) {
+	n := &notify.Notifier{
+		Events:       []string{string(notify.EventPromotionFailed), string(notify.EventPromotionTimedOut)},
+		Environments: []string{"production"},
+	}
+	assert.True(t, n.Matches(&notify.Event{Type: notify.EventPromotionFailed, Environments: []string{"staging", "production"}}))
+	assert.False(t, n.Matches(&notify.Event{Type: notify.EventPromotionSucceeded, Environments: []string{"production"}}))
+	assert.False(t, n.Matches(&notify.Event{Type: notify.EventPromotionTimedOut, Environments: []string{"staging"}}))
+}
+
+func TestNewNotifiersInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec v1alpha1.NotifierSpec
+		err  string
+	}{
+		{
+			name: "kind",
+			spec: v1alpha1.NotifierSpec{Kind: "email", URL: "https://example.com"},
+			err:  "invalid kind email",
+		},
+		{
+			name: "url",
+			spec: v1alpha1.NotifierSpec{Kind: v1alpha1.NotifierKindSlack},
+			err:  "no url",
+		},
+		{
+			name: "env",
+			spec: v1alpha1.NotifierSpec{URLFromEnv: "TEST_NOTIFY_MISSING_URL"},
+			err:  "no $TEST_NOTIFY_MISSING_URL environment variable",
+		},
+		{
+			name: "event",
+			spec: v1alpha1.NotifierSpec{URL: "https://example.com", Events: []string{"Deployed"}},
+			err:  "invalid event Deployed",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := notify.NewNotifiers([]v1alpha1.NotifierSpec{tc.spec})
+		require.Error(t, err, "%s should fail", tc.name)
+		assert.Contains(t, err.Error(), tc.err, "%s error", tc.name)
+	}
+}
@@ -0,0 +1,188 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/activities"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+)
+
+// ApprovalPolicy the reviewers and number of approvals a promotion Pull Request needs before it is merged
+type ApprovalPolicy struct {
+	// Reviewers the user logins or teams in the form 'org/team' whose review is requested
+	Reviewers []string
+
+	// Approvals the number of approvals required before merging
+	Approvals int
+}
+
+// ApprovalPolicy returns the combined approval policy of the given environments or nil if no approvals are required
+func (o *Options) ApprovalPolicy(envs ...*jxcore.EnvironmentConfig) *ApprovalPolicy {
+	policy := &ApprovalPolicy{}
+	for _, env := range envs {
+		envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+		if envSpec == nil {
+			continue
+		}
+		policy.add(envSpec.RequiredReviewers, envSpec.RequiredApprovals)
+		for i := range envSpec.Needs {
+			need := &envSpec.Needs[i]
+			policy.add(need.RequiredReviewers, need.RequiredApprovals)
+		}
+	}
+	if policy.Approvals <= 0 {
+		return nil
+	}
+	return policy
+}
+
+// add adds the reviewers and approvals to the policy defaulting the approvals to 1 if there are reviewers
+func (p *ApprovalPolicy) add(reviewers []string, approvals int) {
+	for _, r := range reviewers {
+		if r != "" && stringhelpers.StringArrayIndex(p.Reviewers, r) < 0 {
+			p.Reviewers = append(p.Reviewers, r)
+		}
+	}
+	if approvals == 0 && len(reviewers) > 0 {
+		approvals = 1
+	}
+	if approvals > p.Approvals {
+		p.Approvals = approvals
+	}
+}
+
+// String returns the description of the policy
+func (p *ApprovalPolicy) String() string {
+	text := fmt.Sprintf("%d approval", p.Approvals)
+	if p.Approvals != 1 {
+		text += "s"
+	}
+	if len(p.Reviewers) > 0 {
+		text += " from " + strings.Join(p.Reviewers, ", ")
+	}
+	return text
+}
+
+// hasTeams returns true if any of the reviewers are teams
+func (p *ApprovalPolicy) hasTeams() bool {
+	for _, r := range p.Reviewers {
+		if strings.Contains(r, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Approvers returns the users who currently approve the Pull Request. Only the latest review of each user counts.
+//
+// If the reviewers are all users then only their approvals count. Team membership cannot be checked so if a team
+// is a reviewer then the approval of any user other than the Pull Request author counts
+func (p *ApprovalPolicy) Approvers(pr *scm.PullRequest, reviews []*scm.Review) []string {
+	latest := map[string]*scm.Review{}
+	for _, r := range reviews {
+		login := r.Author.Login
+		if login == "" || (pr != nil && login == pr.Author.Login) {
+			continue
+		}
+		switch r.State {
+		case scm.ReviewStateApproved, scm.ReviewStateChangesRequested, scm.ReviewStateDismissed:
+		default:
+			// comments do not change whether a user approves
+			continue
+		}
+		current := latest[login]
+		if current == nil || !r.Created.Before(current.Created) {
+			latest[login] = r
+		}
+	}
+
+	restrict := len(p.Reviewers) > 0 && !p.hasTeams()
+	var answer []string
+	for login, r := range latest {
+		if r.State != scm.ReviewStateApproved {
+			continue
+		}
+		if restrict && stringhelpers.StringArrayIndex(p.Reviewers, login) < 0 {
+			continue
+		}
+		answer = append(answer, login)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// RequestReviews requests the review of the required reviewers on the Pull Request
+func (g *GroupContext) RequestReviews(pr *scm.PullRequest, policy *ApprovalPolicy) error {
+	if pr == nil || policy == nil || len(policy.Reviewers) == 0 {
+		return nil
+	}
+	scmClient := g.PullRequest.ScmClient
+	if scmClient == nil {
+		return fmt.Errorf("no ScmClient")
+	}
+	fullName := pr.Repository().FullName
+	_, err := scmClient.PullRequests.RequestReview(context.Background(), fullName, pr.Number, policy.Reviewers)
+	if err != nil {
+		return fmt.Errorf("failed to request review of Pull Request %s from %s: %w", pr.Link, strings.Join(policy.Reviewers, ", "), err)
+	}
+	g.log.Infof("requested review of Pull Request %s from %s", termcolor.ColorInfo(pr.Link), termcolor.ColorInfo(strings.Join(policy.Reviewers, ", ")))
+	return nil
+}
+
+// PullRequestApproved returns whether the Pull Request has the approvals required by the policy along with the
+// current approvers
+func (g *GroupContext) PullRequestApproved(pr *scm.PullRequest, policy *ApprovalPolicy) (bool, []string, error) {
+	if policy == nil {
+		return true, nil, nil
+	}
+	scmClient := g.PullRequest.ScmClient
+	if scmClient == nil {
+		return false, nil, fmt.Errorf("no ScmClient")
+	}
+	fullName := pr.Repository().FullName
+	reviews, _, err := scmClient.Reviews.List(context.Background(), fullName, pr.Number, &scm.ListOptions{})
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to list reviews of Pull Request %s: %w", pr.Link, err)
+	}
+	approvers := policy.Approvers(pr, reviews)
+	return len(approvers) >= policy.Approvals, approvers, nil
+}
+
+// waitForApproval returns whether the Pull Request has been approved. While it is waiting the Pull Request step of
+// the PipelineActivity is marked as waiting for approval
+func (g *GroupContext) waitForApproval(pr *scm.PullRequest, policy *ApprovalPolicy, promoteKey *activities.PromoteStepActivityKey, waiting *bool) (bool, error) {
+	approved, approvers, err := g.PullRequestApproved(pr, policy)
+	if err != nil {
+		return false, err
+	}
+	if approved == !*waiting {
+		return approved, nil
+	}
+	*waiting = !approved
+
+	status := v1.ActivityStatusTypeRunning
+	if approved {
+		g.log.Infof("Pull Request %s has been approved by %s", termcolor.ColorInfo(pr.Link), termcolor.ColorInfo(strings.Join(approvers, ", ")))
+	} else {
+		status = v1.ActivityStatusTypeWaitingForApproval
+		g.log.Infof("Pull Request %s is waiting for %s", termcolor.ColorInfo(pr.Link), termcolor.ColorInfo(policy.String()))
+	}
+	err = g.onPromote(promoteKey, func(ps *v1.PromoteActivityStep) {
+		ps.Status = status
+		if ps.PullRequest != nil {
+			ps.PullRequest.Status = status
+		}
+	})
+	if err != nil {
+		g.log.Warnf("Failed to update PipelineActivity: %s", err)
+	}
+	return approved, nil
+}
@@ -0,0 +1,104 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalPolicy(t *testing.T) {
+	o := &promote.Options{
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:               "production",
+						RequiredReviewers: []string{"alice", "bob"},
+					},
+					{
+						Key:               "dr",
+						RequiredReviewers: []string{"bob", "carol"},
+						RequiredApprovals: 2,
+					},
+				},
+			},
+		},
+	}
+	staging := &jxcore.EnvironmentConfig{Key: "staging"}
+	production := &jxcore.EnvironmentConfig{Key: "production"}
+	dr := &jxcore.EnvironmentConfig{Key: "dr"}
+
+	assert.Nil(t, o.ApprovalPolicy(staging), "staging should not require approvals")
+
+	policy := o.ApprovalPolicy(production)
+	require.NotNil(t, policy, "production should require approvals")
+	assert.Equal(t, 1, policy.Approvals, "production approvals")
+
+	policy = o.ApprovalPolicy(production, dr)
+	require.NotNil(t, policy, "production and dr should require approvals")
+	assert.Equal(t, []string{"alice", "bob", "carol"}, policy.Reviewers, "combined reviewers")
+	assert.Equal(t, 2, policy.Approvals, "combined approvals")
+}
+
+func TestPullRequestApproved(t *testing.T) {
+	scmClient, fakeData := fake.NewDefault()
+	o := &promote.Options{}
+	o.ScmClient = scmClient
+	g := o.NewGroupContext(nil, "")
+
+	pr := &scm.PullRequest{
+		Number: 1,
+		Link:   "https://github.com/myorg/environment-production/pull/1",
+		Author: scm.User{Login: "jx-bot"},
+		Base: scm.PullRequestBranch{
+			Repo: scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: "myorg/environment-production"},
+		},
+	}
+	policy := &promote.ApprovalPolicy{
+		Reviewers: []string{"alice", "bob"},
+		Approvals: 2,
+	}
+	now := time.Now()
+	review := func(login, state string, age time.Duration) *scm.Review {
+		return &scm.Review{Author: scm.User{Login: login}, State: state, Created: now.Add(-age)}
+	}
+
+	fakeData.Reviews[1] = []*scm.Review{
+		review("alice", scm.ReviewStateApproved, time.Hour),
+		review("jx-bot", scm.ReviewStateApproved, time.Hour),
+		review("dave", scm.ReviewStateApproved, time.Hour),
+		review("bob", scm.ReviewStateApproved, 2*time.Hour),
+		review("bob", scm.ReviewStateChangesRequested, time.Hour),
+		review("bob", scm.ReviewStateCommented, time.Minute),
+	}
+	approved, approvers, err := g.PullRequestApproved(pr, policy)
+	require.NoError(t, err, "failed to check approvals")
+	assert.False(t, approved, "should not be approved as bob requested changes")
+	assert.Equal(t, []string{"alice"}, approvers, "approvers")
+
+	fakeData.Reviews[1] = append(fakeData.Reviews[1], review("bob", scm.ReviewStateApproved, 0))
+	approved, approvers, err = g.PullRequestApproved(pr, policy)
+	require.NoError(t, err, "failed to check approvals")
+	assert.True(t, approved, "should be approved")
+	assert.Equal(t, []string{"alice", "bob"}, approvers, "approvers")
+
+	policy.Reviewers = []string{"myorg/sre"}
+	fakeData.Reviews[1] = []*scm.Review{
+		review("alice", scm.ReviewStateApproved, time.Hour),
+		review("dave", scm.ReviewStateApproved, time.Hour),
+	}
+	approved, approvers, err = g.PullRequestApproved(pr, policy)
+	require.NoError(t, err, "failed to check approvals")
+	assert.True(t, approved, "should be approved by any users when a team is a reviewer")
+	assert.Equal(t, []string{"alice", "dave"}, approvers, "approvers")
+}
@@ -0,0 +1,189 @@
+package promote
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// App an application version to promote
+type App struct {
+	// Name the name of the application chart
+	Name string `json:"name"`
+
+	// Version the version to promote. If not specified the latest version in the helm repositories is used
+	Version string `json:"version,omitempty"`
+
+	// Alias the optional alias used for the chart
+	Alias string `json:"alias,omitempty"`
+
+	// GitURL the git URL of the application. Only required if using file or kpt rules
+	GitURL string `json:"gitURL,omitempty"`
+
+	// ReleaseName the name of the helm release. Defaults to the name
+	ReleaseName string `json:"releaseName,omitempty"`
+
+	// HelmRepositoryURL the helm repository URL of the application. Defaults to the --helm-repo-url option
+	HelmRepositoryURL string `json:"helmRepositoryURL,omitempty"`
+
+	// Changelog the optional file to take a changelog from to add to the pull request body
+	Changelog string `json:"changelog,omitempty"`
+}
+
+// AppsManifest lists the applications to promote together in a single Pull Request
+type AppsManifest struct {
+	Apps []App `json:"apps"`
+}
+
+// appFlag supports both the single '--app name' and repeated '--app name=version' forms
+type appFlag struct {
+	o *Options
+}
+
+func (f *appFlag) String() string {
+	if f.o == nil {
+		return ""
+	}
+	return f.o.Application
+}
+
+func (f *appFlag) Set(value string) error {
+	name, version, batch := strings.Cut(value, "=")
+	if !batch {
+		f.o.Application = value
+		return nil
+	}
+	if name == "" || version == "" {
+		return fmt.Errorf("invalid application %s: expected the format 'name=version'", value)
+	}
+	f.o.Apps = append(f.o.Apps, App{
+		Name:    name,
+		Version: version,
+	})
+	return nil
+}
+
+func (f *appFlag) Type() string {
+	return "string"
+}
+
+// LoadApps loads any applications to promote from the --from-file manifest
+func (o *Options) LoadApps() error {
+	if o.AppsFile != "" {
+		data, err := os.ReadFile(o.AppsFile)
+		if err != nil {
+			return fmt.Errorf("failed to load file %s: %w", o.AppsFile, err)
+		}
+		manifest := &AppsManifest{}
+		err = yaml.Unmarshal(data, manifest)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal YAML file %s: %w", o.AppsFile, err)
+		}
+		o.Apps = append(o.Apps, manifest.Apps...)
+		// lets avoid loading the file again if we are run again
+		o.AppsFile = ""
+	}
+	if len(o.Apps) == 0 {
+		return nil
+	}
+	if o.Application != "" && o.Application != o.Apps[0].Name {
+		return fmt.Errorf("cannot specify an application name of %s as well as 'name=version' applications", o.Application)
+	}
+	names := map[string]bool{}
+	for i := range o.Apps {
+		app := &o.Apps[i]
+		if app.Name == "" {
+			return fmt.Errorf("missing name for application %d", i+1)
+		}
+		if names[app.Name] {
+			return fmt.Errorf("application %s is specified more than once", app.Name)
+		}
+		names[app.Name] = true
+	}
+	return nil
+}
+
+// resolveAppVersions defaults the version of each application to promote and uses the first application for the
+// PipelineActivity
+func (o *Options) resolveAppVersions() error {
+	for i := range o.Apps {
+		app := &o.Apps[i]
+		if app.Version == "" {
+			var err error
+			app.Version, err = o.findLatestVersion(app.Name)
+			if err != nil {
+				return fmt.Errorf("failed to find latest version of app %s: %w", app.Name, err)
+			}
+			log.Logger().Infof("defaulting app %s to the latest version %s", termcolor.ColorInfo(app.Name), termcolor.ColorInfo(app.Version))
+		}
+	}
+	first := o.Apps[0]
+	o.Application = first.Name
+	o.Version = first.Version
+	o.ReleaseName = first.ReleaseName
+	return nil
+}
+
+// PromoteApps returns the applications to promote which is either the batch of applications or the single application
+func (g *GroupContext) PromoteApps() []App {
+	if len(g.Apps) > 0 {
+		return g.Apps
+	}
+	return []App{
+		{
+			Name:              g.Application,
+			Version:           g.Version,
+			Alias:             g.Alias,
+			GitURL:            g.AppGitURL,
+			ReleaseName:       g.ReleaseName,
+			HelmRepositoryURL: g.HelmRepositoryURL,
+			Changelog:         g.AddChangelog,
+		},
+	}
+}
+
+// FullAppName returns the application name prefixed with the local helm repository name
+func (o *Options) FullAppName(app string) string {
+	if o.LocalHelmRepoName != "" {
+		return o.LocalHelmRepoName + "/" + app
+	}
+	return app
+}
+
+// appsTitle returns the description of the applications and versions being promoted
+func appsTitle(apps []App) string {
+	var texts []string
+	for i := range apps {
+		version := apps[i].Version
+		if version == "" {
+			version = "latest"
+		}
+		texts = append(texts, fmt.Sprintf("%s to version %s", apps[i].Name, version))
+	}
+	return strings.Join(texts, ", ")
+}
+
+// appsChangelog combines the changelog files of the applications. The heading of the first application is added
+// when the pull request is created
+func appsChangelog(apps []App) (string, error) {
+	answer := ""
+	for i := range apps {
+		app := &apps[i]
+		if app.Changelog == "" {
+			continue
+		}
+		data, err := os.ReadFile(app.Changelog)
+		if err != nil {
+			return "", fmt.Errorf("failed to read changelog file %s: %w", app.Changelog, err)
+		}
+		if i > 0 {
+			answer += fmt.Sprintf("\n# %s\n\n", app.Name)
+		}
+		answer += string(data)
+	}
+	return answer, nil
+}
@@ -0,0 +1,57 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppFlags(t *testing.T) {
+	cmd, o := promote.NewCmdPromote()
+	err := cmd.ParseFlags([]string{"--app", "frontend=1.2.3", "--app", "backend=2.0.1"})
+	require.NoError(t, err, "failed to parse flags")
+
+	err = o.LoadApps()
+	require.NoError(t, err, "failed to load apps")
+
+	assert.Empty(t, o.Application, "should not have a single application")
+	assert.Equal(t, []promote.App{
+		{Name: "frontend", Version: "1.2.3"},
+		{Name: "backend", Version: "2.0.1"},
+	}, o.NewGroupContext(nil, o.Version).PromoteApps())
+
+	cmd, o = promote.NewCmdPromote()
+	err = cmd.ParseFlags([]string{"--app", "myapp"})
+	require.NoError(t, err, "failed to parse flags")
+	assert.Equal(t, "myapp", o.Application, "single application")
+
+	cmd, _ = promote.NewCmdPromote()
+	err = cmd.ParseFlags([]string{"--app", "myapp="})
+	require.Error(t, err, "should fail for a missing version")
+}
+
+func TestLoadAppsFromFile(t *testing.T) {
+	_, o := promote.NewCmdPromote()
+	o.AppsFile = filepath.Join("test_data", "apps", "apps.yaml")
+
+	err := o.LoadApps()
+	require.NoError(t, err, "failed to load apps from %s", o.AppsFile)
+
+	apps := o.NewGroupContext(nil, o.Version).PromoteApps()
+	require.Len(t, apps, 2, "apps loaded from %s", o.AppsFile)
+	assert.Equal(t, "frontend", apps[0].Name)
+	assert.Equal(t, "https://github.com/myorg/frontend.git", apps[0].GitURL)
+	assert.Equal(t, "backend", apps[1].Name)
+	assert.Equal(t, "2.0.1", apps[1].Version)
+	assert.Equal(t, "api", apps[1].Alias)
+
+	o.Apps = append(o.Apps, promote.App{Name: "frontend", Version: "1.2.4"})
+	err = o.LoadApps()
+	require.Error(t, err, "should fail for a duplicate application")
+}
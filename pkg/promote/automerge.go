@@ -0,0 +1,178 @@
+package promote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+)
+
+const (
+	enableAutoMergeMutation = `mutation($id: ID!, $method: PullRequestMergeMethod!, $headline: String, $body: String) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method, commitHeadline: $headline, commitBody: $body}) {
+    clientMutationId
+  }
+}`
+
+	enqueuePullRequestMutation = `mutation($id: ID!) {
+  enqueuePullRequest(input: {pullRequestId: $id}) {
+    clientMutationId
+  }
+}`
+)
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// EnableNativeAutoMerge hands the Pull Request promoting to the environments to the native auto merge of the git
+// provider so that it is merged by the provider once its checks pass. For GitHub this enables auto merge which adds
+// the Pull Request to the merge queue if the branch has one and for GitLab the merge request is merged when the
+// pipeline succeeds. Returns false if the git provider does not support it so that the Pull Request should be merged
+// by polling
+func (g *GroupContext) EnableNativeAutoMerge(envs []*jxcore.EnvironmentConfig, pr *scm.PullRequest) (bool, error) {
+	scmClient := g.PullRequest.ScmClient
+	if scmClient == nil {
+		return false, fmt.Errorf("no ScmClient")
+	}
+	options, err := g.MergeOptions(envs, pr)
+	if err != nil {
+		return false, fmt.Errorf("failed to create the options to merge Pull Request %s: %w", pr.Link, err)
+	}
+	ctx := context.Background()
+	fullName := pr.Repository().FullName
+
+	switch scmClient.Driver {
+	case scm.DriverGithub:
+		var message string
+		message, err = g.MergeCommitMessage(envs, pr)
+		if err != nil {
+			return false, fmt.Errorf("failed to create the options to merge Pull Request %s: %w", pr.Link, err)
+		}
+		err = enableGitHubAutoMerge(ctx, scmClient, fullName, pr.Number, options, message)
+	case scm.DriverGitlab:
+		options.MergeWhenPipelineSucceeds = true
+		_, err = scmClient.PullRequests.Merge(ctx, fullName, pr.Number, options)
+	default:
+		g.log.Infof("native auto merge is not supported by git provider %s so merging Pull Request %s when its checks pass", scmClient.Driver.String(), termcolor.ColorInfo(pr.Link))
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to enable auto merge of Pull Request %s: %w", pr.Link, err)
+	}
+	g.log.Infof("enabled the native auto merge of Pull Request %s", termcolor.ColorInfo(pr.Link))
+	return true, nil
+}
+
+// enableGitHubAutoMerge enables the auto merge of the GitHub Pull Request. If the Pull Request can already be merged
+// GitHub rejects enabling auto merge so it is added to the merge queue of the branch instead
+func enableGitHubAutoMerge(ctx context.Context, scmClient *scm.Client, fullName string, number int, options *scm.PullRequestMergeOptions, message string) error {
+	id, err := gitHubPullRequestNodeID(ctx, scmClient, fullName, number)
+	if err != nil {
+		return err
+	}
+	method := strings.ToUpper(options.MergeMethod)
+	if method == "" {
+		method = "MERGE"
+	}
+	variables := map[string]interface{}{
+		"id":       id,
+		"method":   method,
+		"headline": options.CommitTitle,
+	}
+	if message != "" {
+		variables["body"] = message
+	}
+	err = gitHubMutation(ctx, scmClient, enableAutoMergeMutation, variables)
+	if err != nil && strings.Contains(err.Error(), "clean status") {
+		return gitHubMutation(ctx, scmClient, enqueuePullRequestMutation, map[string]interface{}{"id": id})
+	}
+	return err
+}
+
+// gitHubPullRequestNodeID returns the GraphQL node ID of the GitHub Pull Request
+func gitHubPullRequestNodeID(ctx context.Context, scmClient *scm.Client, fullName string, number int) (string, error) {
+	res, err := scmClient.Do(ctx, &scm.Request{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("repos/%s/pulls/%d", fullName, number),
+		Header: http.Header{"Accept": []string{"application/vnd.github+json"}},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the Pull Request: %w", err)
+	}
+	if res.Status != http.StatusOK {
+		return "", fmt.Errorf("status %d: %s", res.Status, string(data))
+	}
+	pr := struct {
+		NodeID string `json:"node_id"`
+	}{}
+	err = json.Unmarshal(data, &pr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the Pull Request: %w", err)
+	}
+	if pr.NodeID == "" {
+		return "", fmt.Errorf("no node_id for Pull Request %d of repository %s", number, fullName)
+	}
+	return pr.NodeID, nil
+}
+
+// gitHubMutation invokes the GitHub GraphQL mutation returning any errors it reports
+func gitHubMutation(ctx context.Context, scmClient *scm.Client, mutation string, variables map[string]interface{}) error {
+	body, err := json.Marshal(&graphQLRequest{Query: mutation, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal the GraphQL request: %w", err)
+	}
+	path := "graphql"
+	if scmClient.GraphQLURL != nil {
+		path = scmClient.GraphQLURL.String()
+	}
+	res, err := scmClient.Do(ctx, &scm.Request{
+		Method: http.MethodPost,
+		Path:   path,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the GraphQL response: %w", err)
+	}
+	if res.Status != http.StatusOK {
+		return fmt.Errorf("status %d: %s", res.Status, string(data))
+	}
+	result := &graphQLResponse{}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return fmt.Errorf("failed to parse the GraphQL response: %w", err)
+	}
+	var messages []string
+	for _, e := range result.Errors {
+		messages = append(messages, e.Message)
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ", "))
+	}
+	return nil
+}
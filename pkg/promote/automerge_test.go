@@ -0,0 +1,90 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableNativeAutoMergeGitHub(t *testing.T) {
+	var mutations []map[string]interface{}
+	clean := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/myorg/environment-production/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"number": 1, "node_id": "PR_kwDOA"}`))
+	})
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		request := map[string]interface{}{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		require.NoError(t, err, "failed to decode the GraphQL request")
+		mutations = append(mutations, request)
+		if clean && len(mutations) == 1 {
+			_, _ = w.Write([]byte(`{"errors": [{"message": "Pull request Pull request is in clean status"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	scmClient, err := github.New(server.URL)
+	require.NoError(t, err, "failed to create the github client")
+
+	o := &promote.Options{MergeMethod: "squash"}
+	o.ScmClient = scmClient
+	pr := &scm.PullRequest{
+		Number: 1,
+		Link:   "https://github.com/myorg/environment-production/pull/1",
+		Base: scm.PullRequestBranch{
+			Repo: scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: "myorg/environment-production"},
+		},
+	}
+	envs := []*jxcore.EnvironmentConfig{{Key: "production"}}
+	g := o.NewGroupContext(envs, "")
+
+	enabled, err := g.EnableNativeAutoMerge(envs, pr)
+	require.NoError(t, err, "failed to enable auto merge")
+	assert.True(t, enabled, "auto merge should be enabled")
+	require.Len(t, mutations, 1, "mutations")
+	assert.Contains(t, mutations[0]["query"], "enablePullRequestAutoMerge", "mutation")
+	variables := mutations[0]["variables"].(map[string]interface{})
+	assert.Equal(t, "PR_kwDOA", variables["id"], "Pull Request node ID")
+	assert.Equal(t, "SQUASH", variables["method"], "merge method")
+
+	mutations = nil
+	clean = true
+	enabled, err = g.EnableNativeAutoMerge(envs, pr)
+	require.NoError(t, err, "failed to enqueue the Pull Request")
+	assert.True(t, enabled, "the Pull Request should be added to the merge queue")
+	require.Len(t, mutations, 2, "mutations")
+	assert.Contains(t, mutations[1]["query"], "enqueuePullRequest", "mutation")
+}
+
+func TestEnableNativeAutoMergeUnsupported(t *testing.T) {
+	scmClient, _ := fake.NewDefault()
+	o := &promote.Options{}
+	o.ScmClient = scmClient
+	pr := &scm.PullRequest{
+		Number: 1,
+		Base: scm.PullRequestBranch{
+			Repo: scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: "myorg/environment-production"},
+		},
+	}
+
+	envs := []*jxcore.EnvironmentConfig{{Key: "production"}}
+	enabled, err := o.NewGroupContext(envs, "").EnableNativeAutoMerge(envs, pr)
+	require.NoError(t, err, "should fall back for unsupported git providers")
+	assert.False(t, enabled, "auto merge should not be enabled")
+}
@@ -0,0 +1,59 @@
+package promote
+
+import (
+	"fmt"
+	"strings"
+
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+)
+
+const optionBranchName = "branch-name"
+
+// PromoteBranchName returns the name of the branch of the Pull Request promoting to the environments evaluated from
+// the --branch-name flag or promote configuration or an empty string if the branch name should be generated
+func (g *GroupContext) PromoteBranchName(envs []*jxcore.EnvironmentConfig) (string, error) {
+	templateText := g.BranchNameTemplate
+	if templateText == "" && g.PromoteConfig != nil && g.PromoteConfig.Spec.PullRequest != nil {
+		templateText = g.PromoteConfig.Spec.PullRequest.BranchName
+	}
+	if templateText == "" {
+		return "", nil
+	}
+	data := g.templateData(envs, nil)
+	if data.Version == "" {
+		data.Version = "latest"
+	}
+	text, err := evaluateTemplate("pullRequest.branchName", templateText, data)
+	if err != nil {
+		return "", err
+	}
+	name := ToBranchName(text)
+	if name == "" {
+		return "", fmt.Errorf("branch name template %s evaluated to an empty branch name", templateText)
+	}
+	return name, nil
+}
+
+// ToBranchName converts the text into a valid git branch name by replacing any characters git does not allow
+func ToBranchName(text string) string {
+	buf := strings.Builder{}
+	last := rune(0)
+	for _, r := range strings.TrimSpace(text) {
+		switch {
+		case r <= ' ' || r == 0x7f || strings.ContainsRune("~^:?*[\\", r):
+			r = '-'
+		case r == '.' && (last == '.' || last == '/' || last == 0):
+			r = '-'
+		case r == '/' && (last == '/' || last == 0):
+			continue
+		}
+		buf.WriteRune(r)
+		last = r
+	}
+	name := strings.Trim(buf.String(), "/")
+	name = strings.ReplaceAll(name, "@{", "-{")
+	for strings.HasSuffix(name, ".lock") || strings.HasSuffix(name, ".") {
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".lock"), ".")
+	}
+	return name
+}
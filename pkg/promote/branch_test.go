@@ -0,0 +1,61 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoteBranchName(t *testing.T) {
+	o := &promote.Options{
+		Version: "1.2.3",
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				PullRequest: &v1alpha1.PullRequestTemplate{
+					BranchName: "promote/{{ .Environment }}/{{ .Application }}-{{ .Version }}",
+				},
+			},
+		},
+	}
+	o.Application = "myapp"
+	envs := []*jxcore.EnvironmentConfig{{Key: "staging"}, {Key: "qa"}}
+	g := o.NewGroupContext(envs, o.Version)
+
+	name, err := g.PromoteBranchName(envs)
+	require.NoError(t, err, "failed to create branch name")
+	assert.Equal(t, "promote/staging/myapp-1.2.3", name, "branch name from the promote configuration")
+
+	o.BranchNameTemplate = "jx/{{ .Application }} {{ .Version }}"
+	name, err = g.PromoteBranchName(envs)
+	require.NoError(t, err, "failed to create branch name")
+	assert.Equal(t, "jx/myapp-1.2.3", name, "branch name from the flag")
+
+	o.BranchNameTemplate = "{{ .AppName }}"
+	_, err = g.PromoteBranchName(envs)
+	require.Error(t, err, "should fail with an unknown template field")
+
+	o.BranchNameTemplate = ""
+	o.PromoteConfig = nil
+	name, err = g.PromoteBranchName(envs)
+	require.NoError(t, err, "failed to create branch name")
+	assert.Empty(t, name, "should generate the branch name by default")
+}
+
+func TestToBranchName(t *testing.T) {
+	testCases := map[string]string{
+		"promote/staging/myapp-1.2.3": "promote/staging/myapp-1.2.3",
+		"promote//staging/my app":     "promote/staging/my-app",
+		"/promote/a..b:c~d^e?f*g[h/":  "promote/a.-b-c-d-e-f-g-h",
+		"promote/.hidden/x.lock":      "promote/-hidden/x",
+	}
+	for text, expected := range testCases {
+		assert.Equal(t, expected, promote.ToBranchName(text), "branch name for %s", text)
+	}
+}
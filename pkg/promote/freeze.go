@@ -0,0 +1,135 @@
+package promote
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// LabelFreeze is the label applied to Pull Requests created during a change freeze
+	LabelFreeze = "do-not-merge/freeze"
+)
+
+// ActiveFreeze a freeze window which is currently active for an environment
+type ActiveFreeze struct {
+	// Environment the key of the frozen environment
+	Environment string
+
+	// Window the active freeze window
+	Window v1alpha1.FreezeWindow
+
+	// Until when the freeze window ends
+	Until time.Time
+}
+
+// Draft returns true if a draft Pull Request should be created during the freeze
+func (f *ActiveFreeze) Draft() bool {
+	return f.Window.Action == v1alpha1.FreezeActionDraft
+}
+
+// String returns the description of the freeze window
+func (f *ActiveFreeze) String() string {
+	name := f.Window.Name
+	if name == "" {
+		name = "change freeze"
+	}
+	if f.Until.IsZero() {
+		return fmt.Sprintf("%s of environment %s", name, f.Environment)
+	}
+	return fmt.Sprintf("%s of environment %s until %s", name, f.Environment, f.Until.Format(time.RFC3339))
+}
+
+// FindActiveFreeze returns the first freeze window of the given environments which is active at the given time
+// or nil if there is none
+func (o *Options) FindActiveFreeze(envs []*jxcore.EnvironmentConfig, t time.Time) (*ActiveFreeze, error) {
+	for _, env := range envs {
+		envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+		if envSpec == nil {
+			continue
+		}
+		for i := range envSpec.FreezeWindows {
+			w := &envSpec.FreezeWindows[i]
+			until, active, err := FreezeWindowEnd(w, t)
+			if err != nil {
+				return nil, fmt.Errorf("invalid freeze window %s of environment %s: %w", w.Name, env.Key, err)
+			}
+			if active {
+				return &ActiveFreeze{
+					Environment: env.Key,
+					Window:      *w,
+					Until:       until,
+				}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// CheckFreeze returns the active freeze of the given environments. An error is returned if a freeze blocks the
+// promotion and has not been overridden
+func (o *Options) CheckFreeze(envs []*jxcore.EnvironmentConfig) (*ActiveFreeze, error) {
+	freeze, err := o.FindActiveFreeze(envs, time.Now())
+	if err != nil || freeze == nil {
+		return nil, err
+	}
+	if o.OverrideFreeze || freeze.Draft() {
+		return freeze, nil
+	}
+	return nil, fmt.Errorf("cannot promote during the %s. Use --%s --%s to override the freeze", freeze.String(), optionOverrideFreeze, optionReason)
+}
+
+// FreezeWindowEnd returns when the freeze window ends and whether it is active at the given time
+func FreezeWindowEnd(w *v1alpha1.FreezeWindow, t time.Time) (time.Time, bool, error) {
+	if w.Schedule == "" {
+		if w.Start == nil && w.End == nil {
+			return time.Time{}, false, fmt.Errorf("no schedule or start and end times specified")
+		}
+		if w.Start != nil && t.Before(w.Start.Time) {
+			return time.Time{}, false, nil
+		}
+		if w.End != nil && !t.Before(w.End.Time) {
+			return time.Time{}, false, nil
+		}
+		if w.End == nil {
+			return time.Time{}, true, nil
+		}
+		return w.End.Time, true, nil
+	}
+
+	if w.Duration == nil || w.Duration.Duration <= 0 {
+		return time.Time{}, false, fmt.Errorf("no duration specified for schedule %s", w.Schedule)
+	}
+	loc := time.UTC
+	if w.TimeZone != "" {
+		var err error
+		loc, err = time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid time zone %s: %w", w.TimeZone, err)
+		}
+	}
+	schedule, err := cron.ParseStandard(w.Schedule)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid schedule %s: %w", w.Schedule, err)
+	}
+
+	// lets find the latest start of the window which could still be active
+	duration := w.Duration.Duration
+	start := schedule.Next(t.In(loc).Add(-duration - time.Second))
+	if start.After(t) {
+		return time.Time{}, false, nil
+	}
+	for {
+		next := schedule.Next(start)
+		if next.After(t) {
+			break
+		}
+		start = next
+	}
+	end := start.Add(duration)
+	return end, t.Before(end), nil
+}
@@ -0,0 +1,114 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func parseTime(t *testing.T, text string) time.Time {
+	answer, err := time.Parse(time.RFC3339, text)
+	require.NoError(t, err, "failed to parse time %s", text)
+	return answer
+}
+
+func TestFreezeWindowEnd(t *testing.T) {
+	holidays := &v1alpha1.FreezeWindow{
+		Name:  "holidays",
+		Start: &metav1.Time{Time: parseTime(t, "2026-12-20T00:00:00Z")},
+		End:   &metav1.Time{Time: parseTime(t, "2027-01-03T00:00:00Z")},
+	}
+	monthEnd := &v1alpha1.FreezeWindow{
+		Name:     "month-end",
+		Schedule: "0 0 28 * *",
+		Duration: &metav1.Duration{Duration: 96 * time.Hour},
+	}
+
+	testCases := []struct {
+		window   *v1alpha1.FreezeWindow
+		time     string
+		active   bool
+		expected string
+	}{
+		{window: holidays, time: "2026-12-19T23:59:59Z"},
+		{window: holidays, time: "2026-12-25T12:00:00Z", active: true, expected: "2027-01-03T00:00:00Z"},
+		{window: holidays, time: "2027-01-03T00:00:00Z"},
+		{window: monthEnd, time: "2026-10-27T23:59:59Z"},
+		{window: monthEnd, time: "2026-10-28T00:00:00Z", active: true, expected: "2026-11-01T00:00:00Z"},
+		{window: monthEnd, time: "2026-10-31T18:00:00Z", active: true, expected: "2026-11-01T00:00:00Z"},
+		{window: monthEnd, time: "2026-11-01T00:00:00Z"},
+	}
+	for _, tc := range testCases {
+		end, active, err := promote.FreezeWindowEnd(tc.window, parseTime(t, tc.time))
+		require.NoError(t, err, "for window %s at %s", tc.window.Name, tc.time)
+		assert.Equal(t, tc.active, active, "active for window %s at %s", tc.window.Name, tc.time)
+		if tc.active {
+			assert.Equal(t, parseTime(t, tc.expected), end.UTC(), "end for window %s at %s", tc.window.Name, tc.time)
+		}
+	}
+}
+
+func TestCheckFreeze(t *testing.T) {
+	now := time.Now()
+	o := &promote.Options{
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key: "production",
+						FreezeWindows: []v1alpha1.FreezeWindow{
+							{
+								Name:  "release",
+								Start: &metav1.Time{Time: now.Add(-time.Hour)},
+								End:   &metav1.Time{Time: now.Add(time.Hour)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	staging := []*jxcore.EnvironmentConfig{{Key: "staging"}}
+	production := []*jxcore.EnvironmentConfig{{Key: "production"}}
+
+	freeze, err := o.CheckFreeze(staging)
+	require.NoError(t, err, "staging is not frozen")
+	assert.Nil(t, freeze, "staging is not frozen")
+
+	_, err = o.CheckFreeze(production)
+	require.Error(t, err, "production is frozen")
+	t.Logf("got expected error: %s", err.Error())
+
+	o.PromoteConfig.Spec.Environments[0].FreezeWindows[0].Action = v1alpha1.FreezeActionDraft
+	freeze, err = o.CheckFreeze(production)
+	require.NoError(t, err, "should create a draft Pull Request")
+	require.NotNil(t, freeze, "production is frozen")
+	assert.True(t, freeze.Draft(), "should create a draft Pull Request")
+
+	o.PromoteConfig.Spec.Environments[0].FreezeWindows[0].Action = v1alpha1.FreezeActionBlock
+	o.OverrideFreeze = true
+	freeze, err = o.CheckFreeze(production)
+	require.NoError(t, err, "should override the freeze")
+	require.NotNil(t, freeze, "production is frozen")
+}
+
+func TestActiveFreezeString(t *testing.T) {
+	freeze := &promote.ActiveFreeze{
+		Environment: "production",
+		Window:      v1alpha1.FreezeWindow{Name: "year end freeze"},
+		Until:       time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC),
+	}
+	assert.Equal(t, "year end freeze of environment production until 2026-01-02T09:00:00Z", freeze.String())
+
+	freeze.Until = time.Time{}
+	assert.Equal(t, "year end freeze of environment production", freeze.String(), "should omit the end of a freeze without one")
+}
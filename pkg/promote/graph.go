@@ -0,0 +1,379 @@
+package promote
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// GraphFormatText renders the promotion graph as text
+	GraphFormatText = "text"
+
+	// GraphFormatDOT renders the promotion graph in the graphviz DOT language
+	GraphFormatDOT = "dot"
+)
+
+// PromoteGraph the groups of environments to promote to and the needs between them
+type PromoteGraph struct {
+	// Nodes the groups of environments in the order they are promoted to
+	Nodes []*GraphNode
+}
+
+// GraphNode a group of environments promoted to via a single Pull Request
+type GraphNode struct {
+	// Environments the environments in the group
+	Environments []*jxcore.EnvironmentConfig
+
+	// Needs the edges from the groups which must be promoted to first
+	Needs []*GraphEdge
+
+	index int
+}
+
+// GraphEdge an edge of the promotion graph from a group which must be promoted to first
+type GraphEdge struct {
+	// From the group which must be promoted to first
+	From *GraphNode
+
+	// Condition when the promotion to the needed group allows the following group to be promoted to
+	Condition v1alpha1.NeedCondition
+
+	// MinSoakTime the minimum time the needed group must have been deployed before the following group is promoted to
+	MinSoakTime time.Duration
+
+	// Approval the approvals required to promote along this edge or nil if there are none
+	Approval *ApprovalPolicy
+}
+
+// Keys returns the keys of the environments in the group
+func (n *GraphNode) Keys() string {
+	return (&GroupResult{Environments: n.Environments}).Keys()
+}
+
+// String returns the description of the conditions of the edge
+func (e *GraphEdge) String() string {
+	var texts []string
+	if e.Condition == v1alpha1.NeedConditionCompleted {
+		texts = append(texts, "when completed")
+	}
+	if e.MinSoakTime > 0 {
+		texts = append(texts, "soak "+e.MinSoakTime.String())
+	}
+	if e.Approval != nil {
+		texts = append(texts, e.Approval.String())
+	}
+	return strings.Join(texts, ", ")
+}
+
+// HasNeeds returns true if any group needs another group to be promoted to first
+func (g *PromoteGraph) HasNeeds() bool {
+	for _, n := range g.Nodes {
+		if len(n.Needs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Groups returns the groups of environments in the order they are promoted to
+func (g *PromoteGraph) Groups() [][]*jxcore.EnvironmentConfig {
+	var groups [][]*jxcore.EnvironmentConfig
+	for _, n := range g.Nodes {
+		groups = append(groups, n.Environments)
+	}
+	return groups
+}
+
+// Render renders the graph in the given format
+func (g *PromoteGraph) Render(format string) (string, error) {
+	switch format {
+	case GraphFormatText:
+		return g.Text(), nil
+	case GraphFormatDOT:
+		return g.DOT(), nil
+	default:
+		return "", fmt.Errorf("invalid graph format %s: expected %s or %s", format, GraphFormatText, GraphFormatDOT)
+	}
+}
+
+// Text renders the graph as text with a line for each group without needs and for each edge
+func (g *PromoteGraph) Text() string {
+	sb := strings.Builder{}
+	for _, n := range g.Nodes {
+		if len(n.Needs) == 0 {
+			sb.WriteString(n.Keys() + "\n")
+			continue
+		}
+		for _, e := range n.Needs {
+			line := e.From.Keys() + " --> " + n.Keys()
+			if label := e.String(); label != "" {
+				line += " (" + label + ")"
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// DOT renders the graph in the graphviz DOT language
+func (g *PromoteGraph) DOT() string {
+	sb := strings.Builder{}
+	sb.WriteString("digraph promotion {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&sb, "  %q;\n", n.Keys())
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.Needs {
+			fmt.Fprintf(&sb, "  %q -> %q", e.From.Keys(), n.Keys())
+			if label := e.String(); label != "" {
+				fmt.Fprintf(&sb, " [label=%q]", label)
+			}
+			sb.WriteString(";\n")
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// needKeys returns the sorted keys of the environments the environment needs
+func (o *Options) needKeys(env *jxcore.EnvironmentConfig) string {
+	envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+	if envSpec == nil {
+		return ""
+	}
+	var keys []string
+	for i := range envSpec.Needs {
+		keys = append(keys, envSpec.Needs[i].Key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// PromoteGraph returns the graph of the groups of environments matching the predicate sorted in the order they are
+// promoted to. Needs on environments which are not promoted to are ignored
+func (o *Options) PromoteGraph(pred func(*jxcore.EnvironmentConfig) bool) (*PromoteGraph, error) {
+	groups := o.PromoteGroups(pred)
+	nodes := make([]*GraphNode, 0, len(groups))
+	nodeByKey := map[string]*GraphNode{}
+	for i, group := range groups {
+		n := &GraphNode{Environments: group, index: i}
+		nodes = append(nodes, n)
+		for _, env := range group {
+			nodeByKey[env.Key] = n
+		}
+	}
+
+	for _, n := range nodes {
+		edges := map[*GraphNode]*GraphEdge{}
+		for _, env := range n.Environments {
+			envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+			if envSpec == nil {
+				continue
+			}
+			for i := range envSpec.Needs {
+				need := &envSpec.Needs[i]
+				needEnv := o.findRequirementsEnvironment(need.Key)
+				if needEnv == nil {
+					return nil, fmt.Errorf("environment %s needs environment %s which is not in the requirements", env.Key, need.Key)
+				}
+				from := nodeByKey[need.Key]
+				if from == nil {
+					continue
+				}
+				if from == n {
+					return nil, fmt.Errorf("environment %s cannot need environment %s as they are promoted to via the same Pull Request", env.Key, need.Key)
+				}
+				edge := edges[from]
+				if edge == nil {
+					edge = &GraphEdge{
+						From:      from,
+						Condition: v1alpha1.NeedConditionCompleted,
+					}
+					edges[from] = edge
+					n.Needs = append(n.Needs, edge)
+				}
+				switch need.Condition {
+				case "", v1alpha1.NeedConditionSucceeded:
+					edge.Condition = v1alpha1.NeedConditionSucceeded
+				case v1alpha1.NeedConditionCompleted:
+				default:
+					return nil, fmt.Errorf("invalid condition %s on the need of environment %s on %s: expected %s or %s", need.Condition, env.Key, need.Key, v1alpha1.NeedConditionSucceeded, v1alpha1.NeedConditionCompleted)
+				}
+				d := o.MinSoakTime(needEnv)
+				if need.MinSoakTime != nil {
+					d = need.MinSoakTime.Duration
+				}
+				if d > edge.MinSoakTime {
+					edge.MinSoakTime = d
+				}
+				policy := &ApprovalPolicy{}
+				if edge.Approval != nil {
+					policy = edge.Approval
+				}
+				policy.add(need.RequiredReviewers, need.RequiredApprovals)
+				if policy.Approvals > 0 {
+					edge.Approval = policy
+				}
+			}
+		}
+	}
+
+	// lets sort the groups so that needed groups are first, otherwise keeping the requirements order
+	sorted := make([]*GraphNode, 0, len(nodes))
+	done := map[*GraphNode]bool{}
+	for len(sorted) < len(nodes) {
+		var next *GraphNode
+		for _, n := range nodes {
+			if !done[n] && n.ready(done) {
+				next = n
+				break
+			}
+		}
+		if next == nil {
+			var keys []string
+			for _, n := range nodes {
+				if !done[n] {
+					keys = append(keys, n.Keys())
+				}
+			}
+			return nil, fmt.Errorf("the needs of environments %s form a cycle", strings.Join(keys, ", "))
+		}
+		done[next] = true
+		next.index = len(sorted)
+		sorted = append(sorted, next)
+	}
+	return &PromoteGraph{Nodes: sorted}, nil
+}
+
+// ready returns true if all the needed groups are done
+func (n *GraphNode) ready(done map[*GraphNode]bool) bool {
+	for _, e := range n.Needs {
+		if !done[e.From] {
+			return false
+		}
+	}
+	return true
+}
+
+// findRequirementsEnvironment returns the environment in the requirements with the given key or nil if there is none
+func (o *Options) findRequirementsEnvironment(key string) *jxcore.EnvironmentConfig {
+	envs := o.DevEnvContext.Requirements.Environments
+	for i := range envs {
+		if envs[i].Key == key {
+			return &envs[i]
+		}
+	}
+	return nil
+}
+
+// promoteGraph promotes to the groups of environments walking the graph using up to --parallel goroutines. Each group
+// is promoted to once the conditions of its needs are met. If they cannot be met the group is skipped
+func (o *Options) promoteGraph(graph *PromoteGraph, version string) ([]*GroupResult, error) {
+	parallel := o.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	nodes := graph.Nodes
+	results := make([]*GroupResult, len(nodes))
+	started := make([]bool, len(nodes))
+	finished := make([]bool, len(nodes))
+	done := make(chan int)
+	running := 0
+	for {
+		progress := true
+		for progress {
+			progress = false
+			for i, n := range nodes {
+				if started[i] || running >= parallel {
+					continue
+				}
+				if !n.finished(finished) {
+					continue
+				}
+				started[i] = true
+				progress = true
+				reason := o.unmetNeeds(n, results)
+				if reason != "" {
+					log.Logger().Warnf("not promoting to environments %s as %s", n.Keys(), reason)
+					results[i] = &GroupResult{Environments: n.Environments, Skipped: true}
+					finished[i] = true
+					continue
+				}
+				soaks := o.needSoaks(n, results)
+				running++
+				if parallel > 1 {
+					log.Logger().Infof("promoting to environments %s", termcolor.ColorInfo(n.Keys()))
+				}
+				go func() {
+					done <- o.promoteNode(n, version, soaks, results)
+				}()
+			}
+		}
+		if running == 0 {
+			break
+		}
+		i := <-done
+		running--
+		finished[i] = true
+	}
+	return results, groupErrors(results)
+}
+
+// promoteNode promotes to the group of environments of the node storing its result and returning its index
+func (o *Options) promoteNode(n *GraphNode, version string, soaks []soak, results []*GroupResult) int {
+	results[n.index] = o.promoteGroup(n.Environments, version, soaks)
+	return n.index
+}
+
+// finished returns true if the promotion of all the needed groups has finished
+func (n *GraphNode) finished(finished []bool) bool {
+	for _, e := range n.Needs {
+		if !finished[e.From.index] {
+			return false
+		}
+	}
+	return true
+}
+
+// unmetNeeds returns the reason the conditions of the needs of the group are not met or an empty string if they are
+func (o *Options) unmetNeeds(n *GraphNode, results []*GroupResult) string {
+	for _, e := range n.Needs {
+		if e.Condition == v1alpha1.NeedConditionCompleted {
+			continue
+		}
+		r := results[e.From.index]
+		switch r.Status() {
+		case GroupStatusSucceeded:
+			continue
+		case GroupStatusPending:
+			if o.NoPoll {
+				continue
+			}
+		}
+		return fmt.Sprintf("the promotion to environments %s is %s", e.From.Keys(), strings.ToLower(string(r.Status())))
+	}
+	return ""
+}
+
+// needSoaks returns the times the needed groups must soak before the group is promoted to
+func (o *Options) needSoaks(n *GraphNode, results []*GroupResult) []soak {
+	var soaks []soak
+	for _, e := range n.Needs {
+		r := results[e.From.index]
+		if r.Skipped {
+			continue
+		}
+		for _, env := range e.From.Environments {
+			soaks = append(soaks, soak{env: env, deployedAt: r.DeployedAt, duration: e.MinSoakTime})
+		}
+	}
+	return soaks
+}
@@ -0,0 +1,150 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPromoteGraph(t *testing.T) {
+	o := newGraphOptions([]v1alpha1.EnvironmentSpec{
+		{
+			Key:         "staging",
+			MinSoakTime: &metav1.Duration{Duration: time.Hour},
+		},
+		{
+			Key:   "prod-eu",
+			Needs: []v1alpha1.EnvironmentNeed{{Key: "staging"}},
+		},
+		{
+			Key:   "prod-us",
+			Needs: []v1alpha1.EnvironmentNeed{{Key: "staging"}},
+		},
+		{
+			Key: "dr",
+			Needs: []v1alpha1.EnvironmentNeed{
+				{
+					Key:       "prod-eu",
+					Condition: v1alpha1.NeedConditionCompleted,
+				},
+				{
+					Key:               "prod-us",
+					MinSoakTime:       &metav1.Duration{Duration: 30 * time.Minute},
+					RequiredReviewers: []string{"alice"},
+				},
+			},
+		},
+	})
+
+	graph, err := o.PromoteGraph(allEnvironments)
+	require.NoError(t, err, "failed to create promotion graph")
+	require.True(t, graph.HasNeeds(), "graph should have needs")
+	require.Len(t, graph.Nodes, 4, "nodes")
+
+	var keys []string
+	for _, n := range graph.Nodes {
+		keys = append(keys, n.Keys())
+	}
+	assert.Equal(t, []string{"staging", "prod-eu", "prod-us", "dr"}, keys, "nodes should be in promotion order")
+
+	dr := graph.Nodes[3]
+	require.Len(t, dr.Needs, 2, "dr needs")
+	assert.Equal(t, v1alpha1.NeedConditionCompleted, dr.Needs[0].Condition, "dr need on prod-eu condition")
+	assert.Equal(t, v1alpha1.NeedConditionSucceeded, dr.Needs[1].Condition, "dr need on prod-us condition")
+	assert.Equal(t, 30*time.Minute, dr.Needs[1].MinSoakTime, "dr need on prod-us soak")
+	assert.Equal(t, time.Hour, graph.Nodes[1].Needs[0].MinSoakTime, "prod-eu should default to the soak time of staging")
+
+	policy := o.ApprovalPolicy(dr.Environments...)
+	require.NotNil(t, policy, "dr should require approvals")
+	assert.Equal(t, []string{"alice"}, policy.Reviewers, "dr reviewers")
+
+	assert.Equal(t, `staging
+staging --> prod-eu (soak 1h0m0s)
+staging --> prod-us (soak 1h0m0s)
+prod-eu --> dr (when completed)
+prod-us --> dr (soak 30m0s, 1 approval from alice)
+`, graph.Text(), "text rendering")
+
+	dot, err := graph.Render(promote.GraphFormatDOT)
+	require.NoError(t, err, "failed to render DOT")
+	assert.Contains(t, dot, `"prod-us" -> "dr" [label="soak 30m0s, 1 approval from alice"];`, "DOT rendering")
+	t.Logf("DOT:\n%s", dot)
+}
+
+func TestPromoteGraphCycle(t *testing.T) {
+	o := newGraphOptions([]v1alpha1.EnvironmentSpec{
+		{
+			Key:   "prod-eu",
+			Needs: []v1alpha1.EnvironmentNeed{{Key: "dr"}},
+		},
+		{
+			Key:   "dr",
+			Needs: []v1alpha1.EnvironmentNeed{{Key: "prod-eu"}},
+		},
+	})
+
+	_, err := o.PromoteGraph(allEnvironments)
+	require.Error(t, err, "should fail with a cycle")
+	assert.Contains(t, err.Error(), "form a cycle", "error")
+}
+
+func TestPromoteGraphWithoutNeeds(t *testing.T) {
+	o := newGraphOptions(nil)
+
+	graph, err := o.PromoteGraph(allEnvironments)
+	require.NoError(t, err, "failed to create promotion graph")
+	assert.False(t, graph.HasNeeds(), "graph should not have needs")
+	require.Len(t, graph.Nodes, 3, "nodes")
+	assert.Equal(t, "dr, prod-eu", graph.Nodes[1].Keys(), "environments sharing a repository should be grouped")
+}
+
+func allEnvironments(env *jxcore.EnvironmentConfig) bool {
+	return env.Key != "dev"
+}
+
+func newGraphOptions(envs []v1alpha1.EnvironmentSpec) *promote.Options {
+	o := &promote.Options{
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: envs,
+			},
+		},
+	}
+	o.DevEnvContext.Requirements = &jxcore.RequirementsConfig{
+		Environments: []jxcore.EnvironmentConfig{
+			{
+				Key: "dev",
+			},
+			{
+				Key:               "staging",
+				PromotionStrategy: v1.PromotionStrategyTypeAutomatic,
+			},
+			{
+				Key:               "dr",
+				PromotionStrategy: v1.PromotionStrategyTypeAutomatic,
+				GitURL:            "https://github.com/myorg/environment-prod.git",
+			},
+			{
+				Key:               "prod-eu",
+				PromotionStrategy: v1.PromotionStrategyTypeAutomatic,
+				GitURL:            "https://github.com/myorg/environment-prod.git",
+			},
+			{
+				Key:               "prod-us",
+				PromotionStrategy: v1.PromotionStrategyTypeAutomatic,
+				GitURL:            "https://github.com/myorg/environment-prod-us.git",
+			},
+		},
+	}
+	return o
+}
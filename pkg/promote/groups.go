@@ -0,0 +1,385 @@
+package promote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/environments"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/tracing"
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GroupResult the result of promoting to a group of environments which share a Pull Request
+type GroupResult struct {
+	// Environments the environments in the group
+	Environments []*jxcore.EnvironmentConfig
+
+	// ReleaseInfo the details of the promotion
+	ReleaseInfo *ReleaseInfo
+
+	// Skipped true if the group was not promoted to
+	Skipped bool
+
+	// Completed true if the promotion to the group completed
+	Completed bool
+
+	// DeployedAt when the promotion of the group completed or a zero time if it was not waited for
+	DeployedAt time.Time
+
+	// Error the error promoting to the group
+	Error error
+
+	// StartedAt when the promotion to the group started
+	StartedAt time.Time
+
+	// CompletedAt when the promotion to the group finished
+	CompletedAt time.Time
+}
+
+// Keys returns the keys of the environments in the group
+func (r *GroupResult) Keys() string {
+	var keys []string
+	for _, env := range r.Environments {
+		keys = append(keys, env.Key)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// Status returns the status of the promotion to the group
+func (r *GroupResult) Status() GroupStatus {
+	switch {
+	case r.Error != nil:
+		return GroupStatusFailed
+	case r.Skipped:
+		return GroupStatusSkipped
+	case r.Completed:
+		return GroupStatusSucceeded
+	default:
+		return GroupStatusPending
+	}
+}
+
+// GroupContext the state of promoting a version to a single group of environments. The Options are shared by all the
+// groups and only read while promoting so that groups can be promoted concurrently
+type GroupContext struct {
+	*Options
+
+	// PullRequest the options of creating the Pull Request of the group such as its branch, labels and commit message
+	PullRequest environments.EnvironmentPullRequestOptions
+
+	// Version the version promoted to the group
+	Version string
+
+	// AppGitURL the git URL of the application if it is discovered when promoting to the group
+	AppGitURL string
+
+	log groupLogger
+}
+
+// NewGroupContext creates the context of promoting the version to the group of environments
+func (o *Options) NewGroupContext(envs []*jxcore.EnvironmentConfig, version string) *GroupContext {
+	g := &GroupContext{
+		Options:     o,
+		PullRequest: o.pullRequestOptions(),
+		Version:     version,
+		AppGitURL:   o.AppGitURL,
+	}
+	if o.concurrent {
+		g.log.prefix = (&GroupResult{Environments: envs}).Keys() + ": "
+	}
+	return g
+}
+
+// pullRequestOptions returns the options of creating a Pull Request for a group. Only the configuration is copied so
+// that the state of each Pull Request is not shared between groups. The lazily created clients are shared if they
+// have already been created
+func (o *Options) pullRequestOptions() environments.EnvironmentPullRequestOptions {
+	return environments.EnvironmentPullRequestOptions{
+		DevEnvContext:          o.DevEnvContext,
+		Context:                o.Context,
+		ScmClientFactory:       o.ScmClientFactory,
+		Gitter:                 o.Gitter,
+		CommandRunner:          o.CommandRunner,
+		ModifyChartFn:          o.ModifyChartFn,
+		ModifyKptFn:            o.ModifyKptFn,
+		Labels:                 append([]string(nil), o.Labels...),
+		GitKind:                o.GitKind,
+		RemoteName:             o.RemoteName,
+		BaseBranchName:         o.BaseBranchName,
+		ChangelogSeparator:     o.ChangelogSeparator,
+		Namespace:              o.EnvironmentPullRequestOptions.Namespace,
+		JXClient:               o.EnvironmentPullRequestOptions.JXClient,
+		ScmClient:              o.ScmClient,
+		BatchMode:              o.BatchMode,
+		UseGitHubOAuth:         o.UseGitHubOAuth,
+		Fork:                   o.Fork,
+		SparseCheckoutPatterns: o.SparseCheckoutPatterns,
+		Application:            o.Application,
+		SigningKey:             o.SigningKey,
+		SigningFormat:          o.SigningFormat,
+	}
+}
+
+// createClients creates the clients which are otherwise lazily created before promoting to the groups of environments
+// so that they are shared by the groups rather than being created by each group
+func (o *Options) createClients(groups [][]*jxcore.EnvironmentConfig) {
+	o.Helm()
+	o.Git()
+	if o.ScmClient != nil || len(groups) == 0 || groups[0][0].GitURL == "" {
+		return
+	}
+	_, _, err := o.GetScmClient(groups[0][0].GitURL, o.GitKind)
+	if err != nil {
+		log.Logger().Debugf("failed to create the ScmClient before promoting to the groups of environments: %s", err.Error())
+	}
+}
+
+// promoteGroup promotes the given version to a group of environments, waiting for the previous environments to soak
+// first
+func (o *Options) promoteGroup(group []*jxcore.EnvironmentConfig, version string, soaks []soak) *GroupResult {
+	result := &GroupResult{
+		Environments: group,
+		StartedAt:    time.Now(),
+	}
+	defer func() { result.CompletedAt = time.Now() }()
+	firstEnv := group[0]
+
+	ctx, span := tracing.Start(o.Context, "promote-group", attribute.String("promote.environments", result.Keys()))
+	defer func() { tracing.End(span, result.Error) }()
+
+	groupVersion, err := o.ResolveEnvironmentsVersion(group, version)
+	if err != nil {
+		if len(o.Environments) > 0 {
+			result.Error = err
+			return result
+		}
+		log.Logger().Warnf("not promoting to environment %s: %s", firstEnv.Key, err.Error())
+		result.Skipped = true
+		return result
+	}
+	g := o.NewGroupContext(group, groupVersion)
+	g.PullRequest.Context = ctx
+
+	gs := o.groupState(group)
+	if gs != nil && gs.Completed {
+		g.log.Infof("the promotion to environments %s has already completed", termcolor.ColorInfo(result.Keys()))
+		result.Completed = true
+		result.ReleaseInfo = &ReleaseInfo{
+			ReleaseName: o.ReleaseName,
+			FullAppName: o.FullAppName(g.Application),
+			Version:     gs.Version,
+			PullRequestInfo: &scm.PullRequest{
+				Number:   gs.PullRequestNumber,
+				Link:     gs.PullRequestURL,
+				MergeSha: gs.MergeSHA,
+				Merged:   gs.MergeSHA != "",
+			},
+		}
+		return result
+	}
+	pr, err := g.resumePullRequest(gs)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	var releaseInfo *ReleaseInfo
+	if pr != nil {
+		g.log.Infof("resuming the promotion to environments %s using Pull Request %s", termcolor.ColorInfo(result.Keys()), termcolor.ColorInfo(pr.Link))
+		releaseInfo, err = g.resumeReleaseInfo(group, pr)
+	} else {
+		if len(soaks) > 0 {
+			err = g.waitForSoaks(soaks, firstEnv)
+			if err != nil {
+				result.Error = err
+				return result
+			}
+		}
+		releaseInfo, err = g.Promote(group, false, o.NoPoll)
+		if err == nil && releaseInfo != nil {
+			o.recordPullRequest(group, g.Version, releaseInfo.PullRequestInfo)
+		}
+	}
+	result.ReleaseInfo = releaseInfo
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if releaseInfo != nil && releaseInfo.Freeze != nil && !o.OverrideFreeze {
+		g.log.Infof("not waiting for the promotion to complete during the %s", releaseInfo.Freeze.String())
+		return result
+	}
+	if o.NoPoll {
+		return result
+	}
+	err = g.WaitForPromotion(firstEnv, releaseInfo)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if releaseInfo != nil && releaseInfo.PullRequestInfo != nil {
+		result.DeployedAt = time.Now()
+		o.recordPullRequest(group, g.Version, releaseInfo.PullRequestInfo)
+	}
+	if releaseInfo != nil && releaseInfo.PushCommitSHA != "" {
+		result.DeployedAt = time.Now()
+		g.updateGroupState(group, func(gs *GroupState) {
+			gs.Version = g.Version
+			gs.MergeSHA = releaseInfo.PushCommitSHA
+		})
+	}
+	result.Completed = true
+	o.updateGroupState(group, func(gs *GroupState) {
+		gs.Completed = true
+	})
+	return result
+}
+
+// promoteGroupsInSequence promotes to the groups of environments one after another stopping at the first failure
+func (o *Options) promoteGroupsInSequence(groups [][]*jxcore.EnvironmentConfig, version string) ([]*GroupResult, error) {
+	var results []*GroupResult
+	var previous []*jxcore.EnvironmentConfig
+	var deployedAt time.Time
+	for _, group := range groups {
+		result := o.promoteGroup(group, version, o.previousSoaks(previous, deployedAt))
+		results = append(results, result)
+		if result.Error != nil {
+			return results, result.Error
+		}
+		if result.Skipped {
+			continue
+		}
+		previous = group
+		deployedAt = result.DeployedAt
+	}
+	return results, nil
+}
+
+// groupLogger prefixes the log lines of a group with the keys of its environments when groups are promoted
+// concurrently so that the lines of each group can be told apart
+type groupLogger struct {
+	prefix string
+}
+
+// Infof logs at info level
+func (l groupLogger) Infof(format string, args ...interface{}) {
+	log.Logger().Infof("%s"+format, append([]interface{}{l.prefix}, args...)...)
+}
+
+// Warnf logs at warning level
+func (l groupLogger) Warnf(format string, args ...interface{}) {
+	log.Logger().Warnf("%s"+format, append([]interface{}{l.prefix}, args...)...)
+}
+
+// Debugf logs at debug level
+func (l groupLogger) Debugf(format string, args ...interface{}) {
+	log.Logger().Debugf("%s"+format, append([]interface{}{l.prefix}, args...)...)
+}
+
+// promoteGroupsInParallel promotes to the groups of environments concurrently using up to --parallel goroutines.
+//
+// Groups following a group with a minSoakTime are only promoted once the soak time has passed so the groups are
+// split into waves at each group with a minSoakTime. Groups with needs are promoted by promoteGraph instead
+func (o *Options) promoteGroupsInParallel(groups [][]*jxcore.EnvironmentConfig, version string) ([]*GroupResult, error) {
+	var results []*GroupResult
+	var previous []*jxcore.EnvironmentConfig
+	var deployedAt time.Time
+	for _, wave := range o.waves(groups) {
+		waveResults := make([]*GroupResult, len(wave))
+		sem := make(chan struct{}, o.Parallel)
+		wg := sync.WaitGroup{}
+		for i := range wave {
+			wg.Add(1)
+			sem <- struct{}{}
+			log.Logger().Infof("promoting to environments %s", termcolor.ColorInfo((&GroupResult{Environments: wave[i]}).Keys()))
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				waveResults[i] = o.promoteGroup(wave[i], version, o.previousSoaks(previous, deployedAt))
+			}()
+		}
+		wg.Wait()
+
+		results = append(results, waveResults...)
+		err := groupErrors(waveResults)
+		if err != nil {
+			return results, err
+		}
+
+		previous = nil
+		deployedAt = time.Time{}
+		for _, r := range waveResults {
+			if r.Skipped {
+				continue
+			}
+			previous = append(previous, r.Environments...)
+			if r.DeployedAt.After(deployedAt) {
+				deployedAt = r.DeployedAt
+			}
+		}
+	}
+	return results, nil
+}
+
+// waves splits the groups into waves of groups which have no ordering dependency between them so they can be
+// promoted concurrently. Groups without needs are only ordered by the minSoakTime of the groups before them so a new
+// wave starts after each group with a minSoakTime whatever the promotion strategy of the groups
+func (o *Options) waves(groups [][]*jxcore.EnvironmentConfig) [][][]*jxcore.EnvironmentConfig {
+	var waves [][][]*jxcore.EnvironmentConfig
+	var wave [][]*jxcore.EnvironmentConfig
+	for _, group := range groups {
+		wave = append(wave, group)
+		if o.SkipSoak {
+			continue
+		}
+		for _, env := range group {
+			if o.MinSoakTime(env) > 0 {
+				waves = append(waves, wave)
+				wave = nil
+				break
+			}
+		}
+	}
+	if len(wave) > 0 {
+		waves = append(waves, wave)
+	}
+	return waves
+}
+
+// groupErrors combines the errors of the groups
+func groupErrors(results []*GroupResult) error {
+	var errs []error
+	for _, r := range results {
+		if r != nil && r.Error != nil {
+			errs = append(errs, fmt.Errorf("failed to promote to %s: %w", r.Keys(), r.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// logGroupResults logs the outcome of each group of environments
+func logGroupResults(results []*GroupResult) {
+	info := termcolor.ColorInfo
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			log.Logger().Infof("%s: %s %s", info(r.Keys()), termcolor.ColorError("failed"), r.Error.Error())
+		case r.Skipped:
+			log.Logger().Infof("%s: %s", info(r.Keys()), termcolor.ColorWarning("skipped"))
+		case r.ReleaseInfo != nil && r.ReleaseInfo.PullRequestInfo != nil:
+			log.Logger().Infof("%s: %s %s", info(r.Keys()), termcolor.ColorStatus("promoted"), r.ReleaseInfo.PullRequestInfo.Link)
+		case r.ReleaseInfo != nil && r.ReleaseInfo.PushCommitSHA != "":
+			log.Logger().Infof("%s: %s commit %s", info(r.Keys()), termcolor.ColorStatus("pushed"), r.ReleaseInfo.PushCommitSHA)
+		default:
+			log.Logger().Infof("%s: %s", info(r.Keys()), termcolor.ColorStatus("promoted"))
+		}
+	}
+}
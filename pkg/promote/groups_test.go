@@ -0,0 +1,189 @@
+//go:build unit
+// +build unit
+
+package promote
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	v1fake "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWaves(t *testing.T) {
+	auto := v1.PromotionStrategyTypeAutomatic
+	staging := &jxcore.EnvironmentConfig{Key: "staging", PromotionStrategy: auto}
+	qa := &jxcore.EnvironmentConfig{Key: "qa", PromotionStrategy: auto}
+	preprod := &jxcore.EnvironmentConfig{Key: "preprod", PromotionStrategy: auto}
+	production := &jxcore.EnvironmentConfig{Key: "production", PromotionStrategy: v1.PromotionStrategyTypeManual}
+	dr := &jxcore.EnvironmentConfig{Key: "dr", PromotionStrategy: auto}
+	groups := [][]*jxcore.EnvironmentConfig{{staging}, {qa}, {preprod}, {production}, {dr}}
+
+	o := &Options{
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:         "staging",
+						MinSoakTime: &metav1.Duration{Duration: time.Hour},
+					},
+				},
+			},
+		},
+	}
+	waves := o.waves(groups)
+	assert.Equal(t, [][][]*jxcore.EnvironmentConfig{{{staging}}, {{qa}, {preprod}, {production}, {dr}}}, waves,
+		"a new wave should start after staging soaks and the manual group should be promoted concurrently")
+
+	o.SkipSoak = true
+	waves = o.waves(groups)
+	assert.Equal(t, [][][]*jxcore.EnvironmentConfig{groups}, waves, "should use a single wave with --skip-soak")
+}
+
+func TestGroupErrors(t *testing.T) {
+	results := []*GroupResult{
+		{Environments: []*jxcore.EnvironmentConfig{{Key: "staging"}}, Error: errors.New("boom")},
+		{Environments: []*jxcore.EnvironmentConfig{{Key: "qa"}}, Completed: true},
+		nil,
+		{Environments: []*jxcore.EnvironmentConfig{{Key: "production"}}, Error: ErrPromotionTimedOut},
+	}
+	err := groupErrors(results)
+	require.Error(t, err, "should combine the errors of the groups")
+	assert.Contains(t, err.Error(), "failed to promote to staging: boom", "error")
+	assert.Contains(t, err.Error(), "failed to promote to production: timed out", "error")
+	assert.ErrorIs(t, err, ErrPromotionTimedOut, "should wrap the errors of the groups")
+
+	assert.NoError(t, groupErrors(results[1:3]), "should not fail if no group failed")
+}
+
+func TestPromoteGroupsInParallel(t *testing.T) {
+	auto := v1.PromotionStrategyTypeAutomatic
+	staging := &jxcore.EnvironmentConfig{Key: "staging", PromotionStrategy: auto}
+	qa := &jxcore.EnvironmentConfig{Key: "qa", PromotionStrategy: auto}
+	production := &jxcore.EnvironmentConfig{Key: "production", PromotionStrategy: auto}
+	groups := [][]*jxcore.EnvironmentConfig{{staging, qa}, {production}}
+
+	o := &Options{
+		Parallel:     2,
+		Environments: []string{"staging", "qa", "production"},
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:               "production",
+						VersionConstraint: ">= 2.0.0",
+					},
+				},
+			},
+		},
+		state: &stateStore{
+			state: &PromoteState{
+				Groups: []GroupState{
+					{
+						Environments:      []string{"staging", "qa"},
+						Version:           "1.2.3",
+						PullRequestNumber: 7,
+						PullRequestURL:    "https://github.com/myorg/environment-staging/pull/7",
+						MergeSHA:          "abc123",
+						Completed:         true,
+					},
+				},
+			},
+			save: func(*PromoteState) error { return nil },
+		},
+		concurrent:   true,
+		activityLock: &sync.Mutex{},
+	}
+	o.Application = "myapp"
+	o.Labels = []string{"promote"}
+
+	results, err := o.promoteGroupsInParallel(groups, "1.2.3")
+	require.Error(t, err, "the version should not be promoted to production")
+	assert.Contains(t, err.Error(), "failed to promote to production", "error")
+	require.Len(t, results, 2, "results")
+
+	assert.Equal(t, GroupStatusSucceeded, results[0].Status(), "staging, qa status")
+	assert.Equal(t, "abc123", results[0].ReleaseInfo.PullRequestInfo.MergeSha, "staging, qa merge SHA")
+	assert.Equal(t, []*jxcore.EnvironmentConfig{production}, results[1].Environments, "the results should be in the order of the groups")
+	assert.Equal(t, GroupStatusFailed, results[1].Status(), "production status")
+
+	g1 := o.NewGroupContext(groups[0], "1.2.3")
+	g2 := o.NewGroupContext(groups[1], "1.2.4")
+	assert.Equal(t, "staging, qa: ", g1.log.prefix, "log prefix")
+	g1.PullRequest.BranchName = "promote-staging"
+	g1.PullRequest.Labels = append(g1.PullRequest.Labels, "env/staging")
+	g1.PullRequest.CommitTitle = "chore: promote myapp to version 1.2.3"
+	assert.Empty(t, g2.PullRequest.BranchName, "the branch should not be shared")
+	assert.Equal(t, []string{"promote"}, g2.PullRequest.Labels, "the labels should not be shared")
+	assert.Empty(t, g2.PullRequest.CommitTitle, "the commit title should not be shared")
+	assert.Equal(t, "1.2.4", g2.Version, "version")
+	assert.Equal(t, []string{"promote"}, o.Labels, "the options should not be modified")
+	assert.Empty(t, o.BranchName, "the options should not be modified")
+}
+
+func TestPromoteKeysOfConcurrentGroups(t *testing.T) {
+	envs := []*jxcore.EnvironmentConfig{{Key: "staging"}, {Key: "qa"}, {Key: "production"}}
+	var releases []runtime.Object
+	var ingresses []runtime.Object
+	for _, env := range envs {
+		ns := EnvironmentNamespace(env)
+		releases = append(releases,
+			&v1.Release{
+				ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: ns},
+				Spec:       v1.ReleaseSpec{ReleaseNotesURL: "https://github.com/myorg/myapp/releases/tag/v1.2.3"},
+			},
+			&v1.Release{
+				ObjectMeta: metav1.ObjectMeta{Name: "myapp-1.2.3", Namespace: ns},
+			},
+		)
+		ingresses = append(ingresses, &extv1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: ns},
+			Spec: extv1beta1.IngressSpec{
+				Rules: []extv1beta1.IngressRule{{Host: "myapp-" + ns + ".example.com"}},
+			},
+		})
+	}
+
+	o := &Options{
+		Pipeline:     "myorg/myapp/main",
+		ReleaseName:  "myapp",
+		JXClient:     v1fake.NewSimpleClientset(releases...),
+		KubeClient:   fake.NewSimpleClientset(ingresses...),
+		GitInfo:      &giturl.GitRepository{Organisation: "myorg", Name: "myapp"},
+		concurrent:   true,
+		activityLock: &sync.Mutex{},
+	}
+	o.Application = "myapp"
+	t.Setenv("BUILD_NUMBER", "7")
+	o.resolveBuildInfo()
+	assert.Equal(t, "7", o.Build, "build number")
+
+	wg := sync.WaitGroup{}
+	for _, env := range envs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g := o.NewGroupContext([]*jxcore.EnvironmentConfig{env}, "1.2.3")
+			promoteKey := g.CreatePromoteKey(env)
+			assert.Equal(t, "myorg-myapp-main-7", promoteKey.Name, "PipelineActivity name for %s", env.Key)
+			assert.Equal(t, "https://github.com/myorg/myapp/releases/tag/v1.2.3", promoteKey.ReleaseNotesURL, "release notes for %s", env.Key)
+
+			err := g.CommentOnIssues(env, promoteKey)
+			assert.NoError(t, err, "failed to comment on the issues for %s", env.Key)
+			assert.Equal(t, "myapp-"+EnvironmentNamespace(env)+".example.com", promoteKey.ApplicationURL, "application URL for %s", env.Key)
+		}()
+	}
+	wg.Wait()
+}
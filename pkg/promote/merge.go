@@ -0,0 +1,214 @@
+package promote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+)
+
+const (
+	optionMergeMethod = "merge-method"
+
+	// DefaultMergeCommitTitle the default title of the commit merging a promotion Pull Request
+	DefaultMergeCommitTitle = "jx promote automatically merged promotion PR"
+)
+
+// MergeMethods the methods supported to merge a Pull Request
+var MergeMethods = []string{"merge", "squash", "rebase"}
+
+// ValidateMergeMethod validates the method used to merge Pull Requests
+func ValidateMergeMethod(method string) error {
+	if method == "" {
+		return nil
+	}
+	for _, m := range MergeMethods {
+		if m == method {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid merge method %s: expected one of %s", method, strings.Join(MergeMethods, ", "))
+}
+
+// MergeOptions returns the options to merge the Pull Request promoting to the environments using the merge method and
+// merge commit templates of the first environment. The go-scm merge options only have a single commit message field
+// which GitLab uses for the whole message so the mergeCommitMessage is appended to the title for GitLab. For GitHub the
+// message is sent by MergePullRequest and it is not supported by the other git providers
+func (g *GroupContext) MergeOptions(envs []*jxcore.EnvironmentConfig, pr *scm.PullRequest) (*scm.PullRequestMergeOptions, error) {
+	method := g.MergeMethod
+	titleTemplate := DefaultMergeCommitTitle
+	if envSpec := g.mergeEnvironment(envs); envSpec != nil {
+		if envSpec.MergeMethod != "" {
+			method = envSpec.MergeMethod
+		}
+		if envSpec.MergeCommitTitle != "" {
+			titleTemplate = envSpec.MergeCommitTitle
+		}
+	}
+	err := ValidateMergeMethod(method)
+	if err != nil {
+		return nil, err
+	}
+
+	title, err := evaluateTemplate("mergeCommitTitle", titleTemplate, g.templateData(envs, pr))
+	if err != nil {
+		return nil, err
+	}
+	title = strings.TrimSpace(title)
+	message, err := g.MergeCommitMessage(envs, pr)
+	if err != nil {
+		return nil, err
+	}
+	if message != "" {
+		switch g.scmDriver() {
+		case scm.DriverGitlab:
+			title += "\n\n" + message
+		case scm.DriverGithub:
+			// the message is sent by MergePullRequest
+		default:
+			g.log.Warnf("ignoring the mergeCommitMessage as it is only supported by GitHub and GitLab")
+		}
+	}
+	return &scm.PullRequestMergeOptions{
+		CommitTitle: title,
+		MergeMethod: method,
+	}, nil
+}
+
+// MergeCommitMessage returns the message of the commit merging the Pull Request promoting to the environments from the
+// mergeCommitMessage template of the first environment or an empty string if it has none
+func (g *GroupContext) MergeCommitMessage(envs []*jxcore.EnvironmentConfig, pr *scm.PullRequest) (string, error) {
+	envSpec := g.mergeEnvironment(envs)
+	if envSpec == nil || envSpec.MergeCommitMessage == "" {
+		return "", nil
+	}
+	message, err := evaluateTemplate("mergeCommitMessage", envSpec.MergeCommitMessage, g.templateData(envs, pr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(message), nil
+}
+
+// MergePullRequest merges the Pull Request promoting to the environments. For GitHub the merge request is sent
+// directly so that it includes the mergeCommitMessage which the go-scm merge options do not support
+func (g *GroupContext) MergePullRequest(ctx context.Context, envs []*jxcore.EnvironmentConfig, pr *scm.PullRequest) (*scm.Response, error) {
+	scmClient := g.PullRequest.ScmClient
+	if scmClient == nil {
+		return nil, fmt.Errorf("no ScmClient")
+	}
+	options, err := g.MergeOptions(envs, pr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the options to merge Pull Request %s: %w", pr.Link, err)
+	}
+	fullName := pr.Repository().FullName
+	if scmClient.Driver != scm.DriverGithub {
+		return scmClient.PullRequests.Merge(ctx, fullName, pr.Number, options)
+	}
+	message, err := g.MergeCommitMessage(envs, pr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the options to merge Pull Request %s: %w", pr.Link, err)
+	}
+	if message == "" {
+		return scmClient.PullRequests.Merge(ctx, fullName, pr.Number, options)
+	}
+	return mergeGitHubPullRequest(ctx, scmClient, fullName, pr.Number, options, message)
+}
+
+// mergeGitHubPullRequest merges the GitHub Pull Request with the commit message
+func mergeGitHubPullRequest(ctx context.Context, scmClient *scm.Client, fullName string, number int, options *scm.PullRequestMergeOptions, message string) (*scm.Response, error) {
+	body, err := json.Marshal(map[string]string{
+		"merge_method":   options.MergeMethod,
+		"commit_title":   options.CommitTitle,
+		"commit_message": message,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the merge request: %w", err)
+	}
+	res, err := scmClient.Do(ctx, &scm.Request{
+		Method: http.MethodPut,
+		Path:   fmt.Sprintf("repos/%s/pulls/%d/merge", fullName, number),
+		Header: http.Header{
+			"Accept":       []string{"application/vnd.github+json"},
+			"Content-Type": []string{"application/json"},
+		},
+		Body: bytes.NewReader(body),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res, fmt.Errorf("failed to read the merge response: %w", err)
+	}
+	if res.Status != http.StatusOK {
+		return res, fmt.Errorf("status %d: %s", res.Status, string(data))
+	}
+	return res, nil
+}
+
+// MergeIfMergeable merges the Pull Request promoting to the environments if the git provider reports that it can be
+// merged. Conflicting Pull Requests and those whose mergeability is still being computed are left to the RebasePolicy.
+// Returns the error of a failed merge which can clear such as pending reviews or branch protection checks so the merge
+// should be retried and an error if the merge can never succeed such as the merge method not being allowed
+func (g *GroupContext) MergeIfMergeable(ctx context.Context, envs []*jxcore.EnvironmentConfig, pr *scm.PullRequest) (retryErr, err error) {
+	if !isMergeable(g.scmDriver(), pr) {
+		g.log.Infof("waiting for the git provider to report that Pull Request %s can be merged", termcolor.ColorInfo(pr.Link))
+		return nil, nil
+	}
+	res, err := g.MergePullRequest(ctx, envs, pr)
+	if err == nil {
+		return nil, nil
+	}
+	if isPermanentMergeFailure(res, err) {
+		return nil, fmt.Errorf("failed to merge Pull Request %s: %w", pr.Link, err)
+	}
+	return err, nil
+}
+
+// isMergeable returns true if the git provider reports that the Pull Request can be merged. Git providers whose
+// go-scm driver does not report the mergeability are assumed to be able to merge it
+func isMergeable(driver scm.Driver, pr *scm.PullRequest) bool {
+	switch driver {
+	case scm.DriverGithub, scm.DriverGitlab, scm.DriverGitea, scm.DriverGogs, scm.DriverBitbucket, scm.DriverFake:
+		return pr.Mergeable || pr.MergeableState == scm.MergeableStateMergeable
+	default:
+		return true
+	}
+}
+
+// isPermanentMergeFailure returns true if the git provider rejected the merge method of the Pull Request which
+// retrying does not fix. Git providers also reject merging Pull Requests which conflict or are blocked by branch
+// protection with the same status codes so the message is checked too
+func isPermanentMergeFailure(res *scm.Response, err error) bool {
+	if res == nil || (res.Status != http.StatusMethodNotAllowed && res.Status != http.StatusUnprocessableEntity) {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "not allowed on this repository") || strings.Contains(message, "merge method")
+}
+
+// mergeEnvironment returns the configuration of the first environment whose merge settings are used
+func (g *GroupContext) mergeEnvironment(envs []*jxcore.EnvironmentConfig) *v1alpha1.EnvironmentSpec {
+	if len(envs) == 0 {
+		return nil
+	}
+	return promoteconfig.FindEnvironment(g.PromoteConfig, envs[0].Key)
+}
+
+// scmDriver returns the driver of the git provider or an unknown driver if there is no ScmClient
+func (g *GroupContext) scmDriver() scm.Driver {
+	if g.PullRequest.ScmClient == nil {
+		return scm.DriverUnknown
+	}
+	return g.PullRequest.ScmClient.Driver
+}
@@ -0,0 +1,146 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeOptions(t *testing.T) {
+	o := &promote.Options{
+		Version:     "1.2.3",
+		MergeMethod: "squash",
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:                "production",
+						MergeMethod:        "rebase",
+						MergeCommitTitle:   "chore: promote {{ .Application }} {{ .Version }} to {{ .Environment }} (#{{ .PullRequestNumber }})",
+						MergeCommitMessage: "Promoted via {{ .PullRequestURL }}",
+					},
+				},
+			},
+		},
+	}
+	o.Application = "myapp"
+	pr := &scm.PullRequest{
+		Number: 12,
+		Link:   "https://gitlab.com/myorg/environment-production/-/merge_requests/12",
+	}
+
+	g := o.NewGroupContext(nil, o.Version)
+
+	staging := []*jxcore.EnvironmentConfig{{Key: "staging"}}
+	opts, err := g.MergeOptions(staging, pr)
+	require.NoError(t, err, "failed to create merge options for staging")
+	assert.Equal(t, "squash", opts.MergeMethod, "staging should use the --merge-method")
+	assert.Equal(t, promote.DefaultMergeCommitTitle, opts.CommitTitle, "staging merge commit title")
+
+	production := []*jxcore.EnvironmentConfig{{Key: "production"}}
+	opts, err = g.MergeOptions(production, pr)
+	require.NoError(t, err, "failed to create merge options for production")
+	assert.Equal(t, "rebase", opts.MergeMethod, "production should use its mergeMethod")
+	assert.Equal(t, "chore: promote myapp 1.2.3 to production (#12)", opts.CommitTitle, "production merge commit title")
+
+	g.PullRequest.ScmClient = &scm.Client{Driver: scm.DriverGitlab}
+	opts, err = g.MergeOptions(production, pr)
+	require.NoError(t, err, "failed to create merge options for production on gitlab")
+	assert.Equal(t, "chore: promote myapp 1.2.3 to production (#12)\n\nPromoted via "+pr.Link, opts.CommitTitle, "gitlab merge commit message")
+
+	o.PromoteConfig.Spec.Environments[0].MergeMethod = "fast-forward"
+	_, err = g.MergeOptions(production, pr)
+	require.Error(t, err, "should fail with an invalid merge method")
+	assert.Contains(t, err.Error(), "invalid merge method fast-forward", "error")
+}
+
+func TestMergePullRequestGitHub(t *testing.T) {
+	var requests []map[string]string
+	status := http.StatusOK
+	message := "Pull Request is not mergeable"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/myorg/environment-production/pulls/12/merge", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method, "method")
+		request := map[string]string{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		require.NoError(t, err, "failed to decode the merge request")
+		requests = append(requests, request)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"message": "` + message + `"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	scmClient, err := github.New(server.URL)
+	require.NoError(t, err, "failed to create the github client")
+
+	o := &promote.Options{
+		Version:     "1.2.3",
+		MergeMethod: "squash",
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:                "production",
+						MergeCommitMessage: "Promoted via {{ .PullRequestURL }}",
+					},
+				},
+			},
+		},
+	}
+	o.Application = "myapp"
+	o.ScmClient = scmClient
+	pr := &scm.PullRequest{
+		Number: 12,
+		Link:   "https://github.com/myorg/environment-production/pull/12",
+		Base: scm.PullRequestBranch{
+			Repo: scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: "myorg/environment-production"},
+		},
+	}
+	envs := []*jxcore.EnvironmentConfig{{Key: "production"}}
+	g := o.NewGroupContext(envs, o.Version)
+
+	_, err = g.MergePullRequest(context.Background(), envs, pr)
+	require.NoError(t, err, "failed to merge the Pull Request")
+	require.Len(t, requests, 1, "merge requests")
+	assert.Equal(t, "squash", requests[0]["merge_method"], "merge method")
+	assert.Equal(t, promote.DefaultMergeCommitTitle, requests[0]["commit_title"], "merge commit title")
+	assert.Equal(t, "Promoted via "+pr.Link, requests[0]["commit_message"], "merge commit message")
+
+	status = http.StatusMethodNotAllowed
+	res, err := g.MergePullRequest(context.Background(), envs, pr)
+	require.Error(t, err, "should fail if the Pull Request is not mergeable")
+	require.NotNil(t, res, "response")
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Status, "status")
+
+	pr.Mergeable = true
+	retryErr, err := g.MergeIfMergeable(context.Background(), envs, pr)
+	require.NoError(t, err, "a Pull Request blocked by branch protection should not fail the promotion")
+	assert.Error(t, retryErr, "the merge should be retried")
+
+	message = "Squash merges are not allowed on this repository."
+	_, err = g.MergeIfMergeable(context.Background(), envs, pr)
+	require.Error(t, err, "should fail if the merge method is not allowed")
+	assert.Contains(t, err.Error(), "failed to merge Pull Request "+pr.Link, "error")
+
+	requests = nil
+	pr.Mergeable = false
+	pr.MergeableState = scm.MergeableStateConflicting
+	retryErr, err = g.MergeIfMergeable(context.Background(), envs, pr)
+	require.NoError(t, err, "should not fail for a conflicting Pull Request")
+	assert.NoError(t, retryErr, "should not merge a conflicting Pull Request")
+	assert.Empty(t, requests, "should not try to merge a conflicting Pull Request")
+}
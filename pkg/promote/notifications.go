@@ -0,0 +1,129 @@
+package promote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/notify"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/activities"
+)
+
+// ErrPromotionTimedOut is returned when the promotion does not complete before the --timeout
+var ErrPromotionTimedOut = errors.New("timed out")
+
+// LoadNotifiers creates the notifiers of the promote configuration and the --notify-* options and the sink of the
+// --events-sink option
+func (o *Options) LoadNotifiers() error {
+	var specs []v1alpha1.NotifierSpec
+	if o.PromoteConfig != nil {
+		specs = append(specs, o.PromoteConfig.Spec.Notifiers...)
+	}
+	flags := []struct {
+		kind v1alpha1.NotifierKind
+		urls []string
+	}{
+		{kind: v1alpha1.NotifierKindWebhook, urls: o.NotifyWebhooks},
+		{kind: v1alpha1.NotifierKindSlack, urls: o.NotifySlack},
+		{kind: v1alpha1.NotifierKindTeams, urls: o.NotifyTeams},
+	}
+	for _, f := range flags {
+		for _, u := range f.urls {
+			specs = append(specs, v1alpha1.NotifierSpec{
+				Kind: f.kind,
+				URL:  u,
+			})
+		}
+	}
+	var err error
+	o.notifiers, err = notify.NewNotifiers(specs)
+	if err != nil {
+		return fmt.Errorf("failed to create the notifiers: %w", err)
+	}
+
+	if o.EventsSink == "" {
+		o.EventsSink = os.Getenv("K_SINK")
+	}
+	o.eventsSink = nil
+	if o.EventsSink != "" {
+		o.eventsSink = &notify.CloudEventSink{
+			URL:      o.EventsSink,
+			CDEvents: o.CDEvents,
+		}
+	}
+	return nil
+}
+
+// notify sends the event of promoting to the environment to the notifiers and the events sink. Failures are only
+// logged so that notifications never fail a promotion
+func (g *GroupContext) notify(eventType notify.EventType, env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, promoteKey *activities.PromoteStepActivityKey, err error) {
+	if (len(g.notifiers) == 0 && g.eventsSink == nil) || env == nil {
+		return
+	}
+	e := &notify.Event{
+		Type:        eventType,
+		Application: g.Application,
+		Version:     g.Version,
+		Environment: env.Key,
+		Time:        time.Now(),
+	}
+	if promoteKey != nil {
+		e.PipelineActivity = promoteKey.Name
+	}
+	envs := []*jxcore.EnvironmentConfig{env}
+	if releaseInfo != nil {
+		if len(releaseInfo.Environments) > 0 {
+			envs = releaseInfo.Environments
+		}
+		if releaseInfo.Version != "" {
+			e.Version = releaseInfo.Version
+		}
+		e.ApplicationURL = releaseInfo.ApplicationURL
+		if pr := releaseInfo.PullRequestInfo; pr != nil {
+			e.PullRequestURL = pr.Link
+			e.PullRequestNumber = pr.Number
+		}
+	}
+	for _, env := range envs {
+		e.Environments = append(e.Environments, env.Key)
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	if g.eventsSink != nil {
+		err := g.eventsSink.Send(context.TODO(), e)
+		if err != nil {
+			g.log.Warnf("failed to send event to %s: %s", g.eventsSink.URL, err.Error())
+		}
+	}
+
+	templateText := ""
+	envSpec := promoteconfig.FindEnvironment(g.PromoteConfig, env.Key)
+	if envSpec != nil {
+		templateText = envSpec.NotificationTemplate
+	}
+	for _, n := range g.notifiers {
+		if !n.Matches(e) {
+			continue
+		}
+		err := n.Notify(context.TODO(), e, templateText)
+		if err != nil {
+			g.log.Warnf("failed to notify %s of %s: %s", n.String(), e.Type, err.Error())
+		}
+	}
+}
+
+// notifyFailure sends the event of the promotion to the environment failing or timing out
+func (g *GroupContext) notifyFailure(env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, promoteKey *activities.PromoteStepActivityKey, err error) {
+	eventType := notify.EventPromotionFailed
+	if errors.Is(err, ErrPromotionTimedOut) {
+		eventType = notify.EventPromotionTimedOut
+	}
+	g.notify(eventType, env, releaseInfo, promoteKey, err)
+}
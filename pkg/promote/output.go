@@ -0,0 +1,205 @@
+package promote
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	optionOutput = "output"
+
+	// OutputFormatJSON outputs the result as JSON
+	OutputFormatJSON = "json"
+
+	// OutputFormatYAML outputs the result as YAML
+	OutputFormatYAML = "yaml"
+)
+
+// GroupStatus the status of promoting to a group of environments
+type GroupStatus string
+
+const (
+	// GroupStatusSucceeded the promotion completed
+	GroupStatusSucceeded GroupStatus = "Succeeded"
+
+	// GroupStatusPending the Pull Request was created but the promotion was not waited for
+	GroupStatusPending GroupStatus = "Pending"
+
+	// GroupStatusSkipped the group was not promoted to
+	GroupStatusSkipped GroupStatus = "Skipped"
+
+	// GroupStatusFailed the promotion failed
+	GroupStatusFailed GroupStatus = "Failed"
+)
+
+// PromoteOutput the machine readable result of a promotion
+type PromoteOutput struct {
+	// Application the name of the application
+	Application string `json:"application"`
+
+	// Version the version promoted
+	Version string `json:"version,omitempty"`
+
+	// Groups the result of each group of environments
+	Groups []GroupOutput `json:"groups"`
+}
+
+// GroupOutput the result of promoting to a group of environments
+type GroupOutput struct {
+	// Environments the keys of the environments in the group
+	Environments []string `json:"environments"`
+
+	// Status the status of the promotion
+	Status GroupStatus `json:"status"`
+
+	// Error the error message if the promotion failed
+	Error string `json:"error,omitempty"`
+
+	// ReleaseName the name of the helm release
+	ReleaseName string `json:"releaseName,omitempty"`
+
+	// FullAppName the application name including the helm repository prefix
+	FullAppName string `json:"fullAppName,omitempty"`
+
+	// Version the version promoted to the group
+	Version string `json:"version,omitempty"`
+
+	// PullRequest the Pull Request of the promotion
+	PullRequest *PullRequestOutput `json:"pullRequest,omitempty"`
+
+	// CommitSHA the SHA of the commit pushed directly to the environment repository
+	CommitSHA string `json:"commitSHA,omitempty"`
+
+	// ApplicationURL the URL the application is available at
+	ApplicationURL string `json:"applicationURL,omitempty"`
+
+	// StartedAt when the promotion of the group started
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt when the promotion of the group completed
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// Duration how long the promotion of the group took
+	Duration string `json:"duration,omitempty"`
+}
+
+// PullRequestOutput the details of a promotion Pull Request
+type PullRequestOutput struct {
+	// Number the number of the Pull Request
+	Number int `json:"number"`
+
+	// URL the URL of the Pull Request
+	URL string `json:"url,omitempty"`
+
+	// State the state of the Pull Request such as 'open', 'closed' or 'merged'
+	State string `json:"state,omitempty"`
+
+	// MergeSHA the merge commit SHA of the Pull Request
+	MergeSHA string `json:"mergeSHA,omitempty"`
+}
+
+// ValidateOutput validates the --output option
+func (o *Options) ValidateOutput() error {
+	switch o.OutputFormat {
+	case "":
+		if o.OutputFile != "" {
+			o.OutputFormat = OutputFormatYAML
+		}
+		return nil
+	case OutputFormatJSON, OutputFormatYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid --%s %s: expected %s or %s", optionOutput, o.OutputFormat, OutputFormatJSON, OutputFormatYAML)
+	}
+}
+
+// CreateOutput creates the machine readable result of the promotion to the groups of environments
+func (o *Options) CreateOutput(results []*GroupResult) *PromoteOutput {
+	answer := &PromoteOutput{
+		Application: o.Application,
+		Version:     o.Version,
+		Groups:      []GroupOutput{},
+	}
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		g := GroupOutput{
+			Status: r.Status(),
+		}
+		for _, env := range r.Environments {
+			g.Environments = append(g.Environments, env.Key)
+		}
+		if r.Error != nil {
+			g.Error = r.Error.Error()
+		}
+		if !r.StartedAt.IsZero() {
+			g.StartedAt = &metav1.Time{Time: r.StartedAt}
+		}
+		if !r.CompletedAt.IsZero() {
+			g.CompletedAt = &metav1.Time{Time: r.CompletedAt}
+			if !r.StartedAt.IsZero() {
+				g.Duration = r.CompletedAt.Sub(r.StartedAt).Round(time.Second).String()
+			}
+		}
+		releaseInfo := r.ReleaseInfo
+		if releaseInfo != nil {
+			g.ReleaseName = releaseInfo.ReleaseName
+			g.FullAppName = releaseInfo.FullAppName
+			g.Version = releaseInfo.Version
+			g.ApplicationURL = releaseInfo.ApplicationURL
+			g.CommitSHA = releaseInfo.PushCommitSHA
+			pr := releaseInfo.PullRequestInfo
+			if pr != nil {
+				g.PullRequest = &PullRequestOutput{
+					Number:   pr.Number,
+					URL:      pr.Link,
+					State:    pr.State,
+					MergeSHA: pr.MergeSha,
+				}
+				if pr.Merged {
+					g.PullRequest.State = "merged"
+				}
+			}
+		}
+		answer.Groups = append(answer.Groups, g)
+	}
+	return answer
+}
+
+// WriteOutput writes the machine readable result of the promotion to the --output-file or the standard output
+func (o *Options) WriteOutput(results []*GroupResult) error {
+	if o.OutputFormat == "" {
+		return nil
+	}
+	output := o.CreateOutput(results)
+	var data []byte
+	var err error
+	if o.OutputFormat == OutputFormatJSON {
+		data, err = json.MarshalIndent(output, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(output)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal the promotion result to %s: %w", o.OutputFormat, err)
+	}
+	if o.OutputFile == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	err = os.WriteFile(o.OutputFile, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to save file %s: %w", o.OutputFile, err)
+	}
+	log.Logger().Infof("saved the promotion result to %s", termcolor.ColorInfo(o.OutputFile))
+	return nil
+}
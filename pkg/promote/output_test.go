@@ -0,0 +1,88 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOutput(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "result.json")
+	o := &promote.Options{
+		Version:      "1.2.3",
+		OutputFormat: "json",
+		OutputFile:   outputFile,
+	}
+	o.Application = "myapp"
+	require.NoError(t, o.ValidateOutput(), "should be a valid output format")
+
+	started := time.Now().Add(-90 * time.Second)
+	results := []*promote.GroupResult{
+		{
+			Environments: []*jxcore.EnvironmentConfig{{Key: "staging"}, {Key: "qa"}},
+			Completed:    true,
+			StartedAt:    started,
+			CompletedAt:  started.Add(time.Minute),
+			ReleaseInfo: &promote.ReleaseInfo{
+				ReleaseName:    "myapp",
+				FullAppName:    "dev/myapp",
+				Version:        "1.2.3",
+				ApplicationURL: "https://myapp-staging.example.com",
+				PullRequestInfo: &scm.PullRequest{
+					Number:   5,
+					Link:     "https://github.com/myorg/environment-dev/pull/5",
+					State:    "closed",
+					Merged:   true,
+					MergeSha: "5e3b9a2",
+				},
+			},
+		},
+		{
+			Environments: []*jxcore.EnvironmentConfig{{Key: "production"}},
+			Error:        errors.New("timed out"),
+		},
+	}
+	err := o.WriteOutput(results)
+	require.NoError(t, err, "failed to write output")
+
+	data, err := os.ReadFile(outputFile)
+	require.NoError(t, err, "failed to read %s", outputFile)
+	output := &promote.PromoteOutput{}
+	err = json.Unmarshal(data, output)
+	require.NoError(t, err, "failed to parse %s", outputFile)
+
+	assert.Equal(t, "myapp", output.Application)
+	assert.Equal(t, "1.2.3", output.Version)
+	require.Len(t, output.Groups, 2, "groups")
+
+	staging := output.Groups[0]
+	assert.Equal(t, []string{"staging", "qa"}, staging.Environments)
+	assert.Equal(t, promote.GroupStatusSucceeded, staging.Status)
+	assert.Equal(t, "dev/myapp", staging.FullAppName)
+	assert.Equal(t, "https://myapp-staging.example.com", staging.ApplicationURL)
+	assert.Equal(t, "1m0s", staging.Duration)
+	require.NotNil(t, staging.PullRequest, "staging Pull Request")
+	assert.Equal(t, 5, staging.PullRequest.Number)
+	assert.Equal(t, "merged", staging.PullRequest.State)
+	assert.Equal(t, "5e3b9a2", staging.PullRequest.MergeSHA)
+
+	production := output.Groups[1]
+	assert.Equal(t, promote.GroupStatusFailed, production.Status)
+	assert.Equal(t, "timed out", production.Error)
+	assert.Nil(t, production.PullRequest, "production Pull Request")
+
+	o.OutputFormat = "xml"
+	assert.Error(t, o.ValidateOutput(), "should fail for an invalid output format")
+}
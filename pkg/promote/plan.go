@@ -0,0 +1,501 @@
+package promote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/environments"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/requirements"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// PlanOptions the options for previewing a promotion
+type PlanOptions struct {
+	Options
+
+	Graph string
+}
+
+// PromotePlan the preview of the Pull Requests a promotion would create
+type PromotePlan struct {
+	// Application the name of the application
+	Application string `json:"application"`
+
+	// Version the version to promote
+	Version string `json:"version,omitempty"`
+
+	// Groups the groups of environments promoted via a single Pull Request in promotion order
+	Groups []PlanGroup `json:"groups"`
+}
+
+// PlanGroup the preview of promoting to a group of environments via a single Pull Request
+type PlanGroup struct {
+	// Environments the environments in the group
+	Environments []PlanEnvironment `json:"environments"`
+
+	// Version the version to promote to the group
+	Version string `json:"version,omitempty"`
+
+	// Needs the groups of environments which must be promoted to first
+	Needs []PlanNeed `json:"needs,omitempty"`
+
+	// Skipped the reason the group would not be promoted to
+	Skipped string `json:"skipped,omitempty"`
+
+	// GitURL the git URL of the repository the Pull Request would be created on
+	GitURL string `json:"gitURL,omitempty"`
+
+	// DevRepository true if the git URL defaulted to the repository of the dev environment
+	DevRepository bool `json:"devRepository,omitempty"`
+
+	// PromotionMode how the changes would be promoted: 'pullRequest' or 'push'
+	PromotionMode string `json:"promotionMode,omitempty"`
+
+	// Draft true if the Pull Request would be held from merging
+	Draft bool `json:"draft,omitempty"`
+
+	// AutoMerge true if the Pull Request would be labelled to be merged by the git provider
+	AutoMerge bool `json:"autoMerge,omitempty"`
+
+	// NativeAutoMerge true if the Pull Request would be handed to the auto merge or merge queue of the git provider
+	NativeAutoMerge bool `json:"nativeAutoMerge,omitempty"`
+
+	// Merge true if the promotion would merge the Pull Request once its checks pass
+	Merge bool `json:"merge,omitempty"`
+
+	// Labels the labels of the Pull Request
+	Labels []string `json:"labels,omitempty"`
+
+	// Approval the reviewer approvals required before merging
+	Approval string `json:"approval,omitempty"`
+
+	// Freeze the active change freeze of the environments
+	Freeze string `json:"freeze,omitempty"`
+}
+
+// PlanNeed the preview of a group of environments which must be promoted to before another group
+type PlanNeed struct {
+	// Environments the keys of the environments which must be promoted to first
+	Environments []string `json:"environments"`
+
+	// Condition when the promotion to the environments allows the following group to be promoted to
+	Condition string `json:"condition"`
+
+	// MinSoakTime the minimum time the environments must have been deployed before the following group is promoted to
+	MinSoakTime string `json:"minSoakTime,omitempty"`
+
+	// Approval the approvals required to promote after the environments
+	Approval string `json:"approval,omitempty"`
+}
+
+// PlanEnvironment the preview of promoting to an environment
+type PlanEnvironment struct {
+	// Key the key of the environment
+	Key string `json:"key"`
+
+	// Namespace the namespace the application would be promoted to
+	Namespace string `json:"namespace"`
+
+	// PromotionStrategy the promotion strategy of the environment
+	PromotionStrategy string `json:"promotionStrategy,omitempty"`
+
+	// ReusePullRequest true if an existing Pull Request for the environment would be reused
+	ReusePullRequest bool `json:"reusePullRequest,omitempty"`
+
+	// MinSoakTime the time to wait after promoting to the environment before promoting to the following environments
+	MinSoakTime string `json:"minSoakTime,omitempty"`
+
+	// Rule the kind of rule the promote configuration of the environment repository would use
+	Rule string `json:"rule,omitempty"`
+
+	// RulePath the path the rule would modify in the environment repository
+	RulePath string `json:"rulePath,omitempty"`
+
+	// PromoteConfigFile the promote configuration file in the environment repository or empty if it is generated
+	PromoteConfigFile string `json:"promoteConfigFile,omitempty"`
+
+	// Error the error discovering the promote configuration
+	Error string `json:"error,omitempty"`
+}
+
+var (
+	planLong = templates.LongDesc(`
+		Previews the environments and Pull Requests a promotion would create.
+
+		The environment repositories are not cloned and nothing is written to them or to the cluster.
+`)
+
+	planExample = templates.Examples(`
+		# Preview promoting a version of the current application to all automatic and manual environments
+		jx promote plan --version 1.2.3 --all
+
+		# Preview promoting the myapp application to production as YAML
+		jx promote plan --app myapp --version 1.2.3 --env production -o yaml
+
+		# Render the promotion graph of all the environments in the graphviz DOT language
+		jx promote plan --all --graph dot | dot -Tpng > promotion.png
+	`)
+)
+
+// NewCmdPromotePlan creates the new command for: jx promote plan
+func NewCmdPromotePlan() (*cobra.Command, *PlanOptions) {
+	opts := &PlanOptions{}
+	cmd := &cobra.Command{
+		Use:     "plan [application]",
+		Short:   "Previews the Pull Requests and Environments a promotion would touch",
+		Long:    planLong,
+		Example: planExample,
+		Run: func(_ *cobra.Command, args []string) {
+			opts.Args = args
+			err := opts.Run()
+			helper.CheckErr(err)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Graph, "graph", "", "", "Renders the promotion graph instead of the plan. Either 'text' or 'dot'")
+
+	opts.addEnvironmentOptions(cmd)
+	opts.AddOptions(cmd)
+	return cmd, opts
+}
+
+// Run implements this command
+func (o *PlanOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate options: %w", err)
+	}
+
+	err = o.ResolveApplications()
+	if err != nil {
+		return err
+	}
+
+	if o.Namespace == "" {
+		return fmt.Errorf("no namespace defined")
+	}
+	if o.GitClient == nil {
+		o.GitClient = cli.NewCLIClient("", o.CommandRunner)
+	}
+
+	// lets avoid cloning the version stream as it is only used when modifying the environment repositories
+	err = o.DevEnvContext.LazyLoadRequirements(o.GitClient, o.JXClient, o.Namespace, o.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to lazy load the EnvironmentContext: %w", err)
+	}
+
+	err = o.loadPromoteConfig()
+	if err != nil {
+		return err
+	}
+
+	if o.ReleaseName == "" {
+		o.ReleaseName = o.Application
+	}
+
+	pred, err := o.EnvironmentPredicate()
+	if err != nil {
+		return err
+	}
+
+	if o.Graph != "" {
+		graph, err := o.PromoteGraph(pred)
+		if err != nil {
+			return fmt.Errorf("failed to create the promotion graph: %w", err)
+		}
+		text, err := graph.Render(o.Graph)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.WriteString(text)
+		return err
+	}
+
+	plan, err := o.CreatePlan(pred)
+	if err != nil {
+		return err
+	}
+	return o.WritePlan(plan)
+}
+
+// CreatePlan creates the preview of promoting to the environments matching the predicate
+func (o *Options) CreatePlan(pred func(*jxcore.EnvironmentConfig) bool) (*PromotePlan, error) {
+	plan := &PromotePlan{
+		Application: o.Application,
+		Version:     o.Version,
+		Groups:      []PlanGroup{},
+	}
+	graph, err := o.PromoteGraph(pred)
+	if err != nil {
+		return plan, fmt.Errorf("failed to create the promotion graph: %w", err)
+	}
+	for _, n := range graph.Nodes {
+		g, err := o.planGroup(n)
+		if err != nil {
+			return plan, err
+		}
+		plan.Groups = append(plan.Groups, *g)
+	}
+	return plan, nil
+}
+
+// planGroup creates the preview of promoting to a group of environments
+func (o *Options) planGroup(n *GraphNode) (*PlanGroup, error) {
+	group := n.Environments
+	firstEnv := group[0]
+	g := &PlanGroup{
+		Version: o.Version,
+	}
+	for _, e := range n.Needs {
+		need := PlanNeed{
+			Condition: string(e.Condition),
+		}
+		for _, env := range e.From.Environments {
+			need.Environments = append(need.Environments, env.Key)
+		}
+		if e.MinSoakTime > 0 {
+			need.MinSoakTime = e.MinSoakTime.String()
+		}
+		if e.Approval != nil {
+			need.Approval = e.Approval.String()
+		}
+		g.Needs = append(g.Needs, need)
+	}
+	for _, env := range group {
+		e := PlanEnvironment{
+			Key:               env.Key,
+			Namespace:         EnvironmentNamespace(env),
+			PromotionStrategy: string(promotionStrategy(env)),
+			ReusePullRequest:  env.ReusePullRequest,
+		}
+		if d := o.MinSoakTime(env); d > 0 {
+			e.MinSoakTime = d.String()
+		}
+		g.Environments = append(g.Environments, e)
+	}
+
+	version, err := o.ResolveEnvironmentsVersion(group, o.Version)
+	if err != nil {
+		if len(o.Environments) > 0 {
+			return nil, err
+		}
+		g.Skipped = err.Error()
+		return g, nil
+	}
+	g.Version = version
+
+	g.GitURL, err = o.EnvironmentGitURL(firstEnv)
+	if err != nil {
+		return nil, err
+	}
+	g.DevRepository = requirements.EnvironmentGitURL(o.DevEnvContext.Requirements, firstEnv.Key) == ""
+
+	freeze, err := o.CheckFreeze(group)
+	if err != nil {
+		return nil, err
+	}
+	if freeze != nil {
+		g.Freeze = freeze.String()
+	}
+	approval := o.ApprovalPolicy(group...)
+	if approval != nil {
+		g.Approval = approval.String()
+	}
+
+	gc := o.NewGroupContext(group, version)
+	mode, err := gc.GroupPromotionMode(group, &ReleaseInfo{Freeze: freeze, Approval: approval})
+	if err != nil {
+		return nil, err
+	}
+	g.PromotionMode = string(mode)
+	if mode == v1alpha1.PromotionModePush {
+		o.planPromoteConfig(g)
+		return g, nil
+	}
+
+	g.Draft = o.draftPullRequest(firstEnv, freeze)
+	g.AutoMerge = o.AutoMerge && !g.Draft
+	g.Merge = !o.NoPoll && !o.NoMergePullRequest && (freeze == nil || o.OverrideFreeze)
+	g.NativeAutoMerge = g.Merge && o.NativeAutoMerge && !g.Draft
+	g.Labels = o.pullRequestLabels(gc.promoteLabels(group), freeze, g.Draft)
+	if g.AutoMerge {
+		g.Labels = append(g.Labels, environments.LabelUpdatebot)
+	}
+
+	o.planPromoteConfig(g)
+	return g, nil
+}
+
+// planPromoteConfig discovers the promote configuration each environment would use via the git provider so that the
+// environment repository does not need to be cloned
+func (o *Options) planPromoteConfig(g *PlanGroup) {
+	scmClient, repoFullName, err := o.GetScmClient(g.GitURL, o.GitKind)
+	if err == nil && scmClient == nil {
+		err = fmt.Errorf("no git provider for %s", g.GitURL)
+	}
+	for i := range g.Environments {
+		e := &g.Environments[i]
+		if err != nil {
+			e.Error = err.Error()
+			continue
+		}
+		config, fileName, err := promoteconfig.DiscoverRepository(context.TODO(), scmClient, repoFullName, o.BaseBranchName, e.Namespace)
+		if err != nil {
+			e.Error = err.Error()
+			continue
+		}
+		e.PromoteConfigFile = fileName
+		e.Rule, e.RulePath = promoteRule(config)
+	}
+}
+
+// promoteRule returns the kind and path of the rule of the promote configuration
+func promoteRule(config *v1alpha1.Promote) (string, string) {
+	spec := &config.Spec
+	switch {
+	case spec.HelmfileRule != nil:
+		return "helmfile", spec.HelmfileRule.Path
+	case spec.HelmRule != nil:
+		return "helm", spec.HelmRule.Path
+	case spec.FileRule != nil:
+		return "file", spec.FileRule.Path
+	case spec.KptRule != nil:
+		return "kpt", spec.KptRule.Path
+	default:
+		return "", ""
+	}
+}
+
+// WritePlan writes the plan in the --output format or as text
+func (o *Options) WritePlan(plan *PromotePlan) error {
+	var data []byte
+	var err error
+	switch o.OutputFormat {
+	case OutputFormatJSON:
+		data, err = json.MarshalIndent(plan, "", "  ")
+		data = append(data, '\n')
+	case OutputFormatYAML:
+		data, err = yaml.Marshal(plan)
+	default:
+		data = []byte(plan.String())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal the promotion plan to %s: %w", o.OutputFormat, err)
+	}
+	if o.OutputFile == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	err = os.WriteFile(o.OutputFile, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to save file %s: %w", o.OutputFile, err)
+	}
+	log.Logger().Infof("saved the promotion plan to %s", termcolor.ColorInfo(o.OutputFile))
+	return nil
+}
+
+// String returns the plan as text
+func (p *PromotePlan) String() string {
+	info := termcolor.ColorInfo
+	sb := strings.Builder{}
+	version := p.Version
+	if version == "" {
+		version = "latest"
+	}
+	fmt.Fprintf(&sb, "promoting app %s version %s\n", info(p.Application), info(version))
+	if len(p.Groups) == 0 {
+		sb.WriteString("\nno environments to promote to\n")
+	}
+	for i := range p.Groups {
+		g := &p.Groups[i]
+		var keys []string
+		for _, e := range g.Environments {
+			keys = append(keys, e.Key)
+		}
+		fmt.Fprintf(&sb, "\n%d. environments %s\n", i+1, info(strings.Join(keys, ", ")))
+		if g.Skipped != "" {
+			fmt.Fprintf(&sb, "   %s: %s\n", termcolor.ColorWarning("skipped"), g.Skipped)
+			continue
+		}
+		for _, need := range g.Needs {
+			fmt.Fprintf(&sb, "   needs:          %s", strings.Join(need.Environments, ", "))
+			if need.Condition == string(v1alpha1.NeedConditionCompleted) {
+				sb.WriteString(" to complete")
+			}
+			if need.MinSoakTime != "" {
+				fmt.Fprintf(&sb, " soaked for %s", need.MinSoakTime)
+			}
+			if need.Approval != "" {
+				fmt.Fprintf(&sb, " with %s", need.Approval)
+			}
+			sb.WriteString("\n")
+		}
+		if g.Version != p.Version {
+			fmt.Fprintf(&sb, "   version:        %s\n", info(g.Version))
+		}
+		repo := g.GitURL
+		if g.DevRepository {
+			repo += " (dev environment repository)"
+		}
+		fmt.Fprintf(&sb, "   repository:     %s\n", repo)
+		if g.PromotionMode == string(v1alpha1.PromotionModePush) {
+			sb.WriteString("   push:           committed directly to the base branch\n")
+		} else {
+			writePlanPullRequest(&sb, g)
+		}
+		if g.Approval != "" {
+			fmt.Fprintf(&sb, "   approval:       %s\n", g.Approval)
+		}
+		if g.Freeze != "" {
+			fmt.Fprintf(&sb, "   freeze:         %s\n", termcolor.ColorWarning(g.Freeze))
+		}
+		for _, e := range g.Environments {
+			fmt.Fprintf(&sb, "   %s: namespace %s strategy %s", info(e.Key), e.Namespace, e.PromotionStrategy)
+			if e.MinSoakTime != "" {
+				fmt.Fprintf(&sb, " soak %s", e.MinSoakTime)
+			}
+			sb.WriteString("\n")
+			switch {
+			case e.Error != "":
+				fmt.Fprintf(&sb, "     promote config: %s\n", termcolor.ColorError(e.Error))
+			case e.PromoteConfigFile != "":
+				fmt.Fprintf(&sb, "     promote config: %s rule %s from %s\n", e.Rule, e.RulePath, e.PromoteConfigFile)
+			default:
+				fmt.Fprintf(&sb, "     promote config: %s rule %s\n", e.Rule, e.RulePath)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// writePlanPullRequest writes how the Pull Request of the group would be created and merged
+func writePlanPullRequest(sb *strings.Builder, g *PlanGroup) {
+	mode := "ready"
+	switch {
+	case g.Draft:
+		mode = "draft"
+	case g.AutoMerge:
+		mode = "auto-merge"
+	}
+	switch {
+	case g.NativeAutoMerge:
+		mode += ", merged by the auto merge or merge queue of the git provider once its checks pass"
+	case g.Merge:
+		mode += ", merged by jx promote once its checks pass"
+	}
+	fmt.Fprintf(sb, "   pull request:   %s\n", mode)
+	fmt.Fprintf(sb, "   labels:         %s\n", strings.Join(g.Labels, ", "))
+}
@@ -0,0 +1,160 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePlan(t *testing.T) {
+	scmClient, fakeData := fake.NewDefault()
+	fakeData.ContentDir = filepath.Join("test_data", "plan")
+
+	o := &promote.Options{
+		Version:           "1.2.3",
+		LocalHelmRepoName: "dev",
+		All:               true,
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:               "production",
+						RequiredReviewers: []string{"alice"},
+					},
+				},
+			},
+		},
+	}
+	o.Application = "myapp"
+	o.GitKind = "github"
+	o.ScmClientFactory.GitServerURL = "https://github.com"
+	o.ScmClientFactory.ScmClient = scmClient
+	o.DevEnvContext.Requirements = &jxcore.RequirementsConfig{
+		Environments: []jxcore.EnvironmentConfig{
+			{
+				Key:     "dev",
+				GitURL:  "https://github.com/myorg/environment-cluster-dev.git",
+				GitKind: "github",
+			},
+			{
+				Key:               "staging",
+				PromotionStrategy: v1.PromotionStrategyTypeAutomatic,
+			},
+			{
+				Key:               "production",
+				PromotionStrategy: v1.PromotionStrategyTypeManual,
+				GitURL:            "https://github.com/myorg/environment-production.git",
+				RemoteCluster:     true,
+			},
+		},
+	}
+
+	pred, err := o.EnvironmentPredicate()
+	require.NoError(t, err, "failed to create environment predicate")
+	plan, err := o.CreatePlan(pred)
+	require.NoError(t, err, "failed to create plan")
+	require.Len(t, plan.Groups, 2, "groups")
+
+	staging := plan.Groups[0]
+	require.Len(t, staging.Environments, 1, "staging environments")
+	assert.Equal(t, "https://github.com/myorg/environment-cluster-dev.git", staging.GitURL, "staging git URL")
+	assert.True(t, staging.DevRepository, "staging should default to the dev environment repository")
+	assert.False(t, staging.Draft, "staging draft")
+	assert.Equal(t, []string{"env/staging", "dependency/dev/myapp"}, staging.Labels, "staging labels")
+	env := staging.Environments[0]
+	assert.Equal(t, "jx-staging", env.Namespace, "staging namespace")
+	assert.Equal(t, "helmfile", env.Rule, "staging rule")
+	assert.Equal(t, "helmfiles/jx-staging/helmfile.yaml", env.RulePath, "staging rule path")
+	assert.Empty(t, env.Error, "staging promote config error")
+
+	production := plan.Groups[1]
+	require.Len(t, production.Environments, 1, "production environments")
+	assert.Equal(t, "https://github.com/myorg/environment-production.git", production.GitURL, "production git URL")
+	assert.False(t, production.DevRepository, "production should use its own repository")
+	assert.True(t, production.Draft, "production should be a draft")
+	assert.Equal(t, []string{"env/production", "dependency/dev/myapp", "do-not-merge/hold"}, production.Labels, "production labels")
+	assert.Equal(t, "1 approval from alice", production.Approval, "production approval")
+	env = production.Environments[0]
+	assert.Equal(t, "helm", env.Rule, "production rule")
+	assert.Equal(t, "env", env.RulePath, "production rule path")
+	assert.Equal(t, ".jx/promote.yaml", env.PromoteConfigFile, "production promote config file")
+
+	t.Logf("plan:\n%s", plan.String())
+}
+
+func TestCreatePlanPromotionMode(t *testing.T) {
+	scmClient, fakeData := fake.NewDefault()
+	fakeData.ContentDir = filepath.Join("test_data", "plan")
+
+	o := &promote.Options{
+		Version:           "1.2.3",
+		LocalHelmRepoName: "dev",
+		All:               true,
+		NativeAutoMerge:   true,
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:           "staging",
+						PromotionMode: v1alpha1.PromotionModePush,
+					},
+				},
+			},
+		},
+	}
+	o.Application = "myapp"
+	o.GitKind = "github"
+	o.ScmClientFactory.GitServerURL = "https://github.com"
+	o.ScmClientFactory.ScmClient = scmClient
+	o.DevEnvContext.Requirements = &jxcore.RequirementsConfig{
+		Environments: []jxcore.EnvironmentConfig{
+			{
+				Key:     "dev",
+				GitURL:  "https://github.com/myorg/environment-cluster-dev.git",
+				GitKind: "github",
+			},
+			{
+				Key:               "staging",
+				PromotionStrategy: v1.PromotionStrategyTypeAutomatic,
+			},
+			{
+				Key:               "qa",
+				PromotionStrategy: v1.PromotionStrategyTypeAutomatic,
+				GitURL:            "https://github.com/myorg/environment-qa.git",
+				RemoteCluster:     true,
+			},
+		},
+	}
+
+	pred, err := o.EnvironmentPredicate()
+	require.NoError(t, err, "failed to create environment predicate")
+	plan, err := o.CreatePlan(pred)
+	require.NoError(t, err, "failed to create plan")
+	require.Len(t, plan.Groups, 2, "groups")
+
+	staging := plan.Groups[0]
+	assert.Equal(t, "push", staging.PromotionMode, "staging promotion mode")
+	assert.False(t, staging.Merge, "staging should not merge a Pull Request")
+	assert.False(t, staging.NativeAutoMerge, "staging native auto merge")
+	assert.Empty(t, staging.Labels, "staging labels")
+
+	qa := plan.Groups[1]
+	assert.Equal(t, "pullRequest", qa.PromotionMode, "qa promotion mode")
+	assert.True(t, qa.Merge, "qa merge")
+	assert.True(t, qa.NativeAutoMerge, "qa should use the native auto merge")
+
+	text := plan.String()
+	assert.Contains(t, text, "push:", "plan text")
+	assert.Contains(t, text, "merged by the auto merge or merge queue of the git provider", "plan text")
+	t.Logf("plan:\n%s", text)
+}
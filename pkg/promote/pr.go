@@ -2,9 +2,10 @@ package promote
 
 import (
 	"fmt"
-	"os"
 
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
 	"github.com/jenkins-x-plugins/jx-promote/pkg/environments"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/hooks"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/requirements"
 
 	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
@@ -16,61 +17,103 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitconfig"
 )
 
-func (o *Options) PromoteViaPullRequest(envs []*jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, draftPR bool) error {
-	version := o.Version
-	versionName := version
-	if versionName == "" {
-		versionName = "latest"
-	}
-	app := o.Application
-
-	source := "promote-" + app + "-" + versionName
-	var labels []string
+func (g *GroupContext) PromoteViaPullRequest(envs []*jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, draftPR bool) error {
+	apps := g.PromoteApps()
 
-	// TODO: Support more labels. I'm thinking owner...
-	for _, env := range envs {
-		envName := env.Key
-		source += "-" + envName
-		labels = append(labels, "env/"+envName)
+	branchName, err := g.PromoteBranchName(envs)
+	if err != nil {
+		return fmt.Errorf("failed to create the name of the Pull Request branch: %w", err)
 	}
-
-	var dependencyLabel = "dependency/" + releaseInfo.FullAppName
-
-	if len(dependencyLabel) > 49 {
-		dependencyLabel = dependencyLabel[:49]
+	if branchName != "" {
+		g.PullRequest.BranchName = branchName
 	}
-	labels = append(labels, dependencyLabel)
+	labels := g.promoteLabels(envs)
 
-	if o.ReusePullRequest && o.PullRequestFilter == nil {
-		o.PullRequestFilter = &environments.PullRequestFilter{Labels: labels}
+	if g.PullRequest.ReusePullRequest && g.PullRequest.PullRequestFilter == nil {
+		g.PullRequest.PullRequestFilter = &environments.PullRequestFilter{Labels: labels}
 		// Clearing so that it can be set for the correct environment on next call
-		defer func() { o.PullRequestFilter = nil }()
+		defer func() { g.PullRequest.PullRequestFilter = nil }()
 	}
 
 	comment := "this commit will trigger a pipeline to [generate the actual kubernetes resources to perform the promotion](https://jayex.io/v3/about/how-it-works/#promotion) which will create a second commit on this Pull Request before it can merge"
 
-	if draftPR {
-		labels = append(labels, "do-not-merge/hold")
+	notes := ""
+	labels = g.pullRequestLabels(labels, releaseInfo.Freeze, draftPR)
+	if freeze := releaseInfo.Freeze; freeze != nil {
+		if g.OverrideFreeze {
+			notes += fmt.Sprintf("\n\n**Change freeze override**: promoting during the %s because: %s", freeze.String(), g.OverrideReason)
+		} else {
+			notes += fmt.Sprintf("\n\nThis Pull Request must not be merged during the %s", freeze.String())
+		}
 	}
 
-	o.CommitTitle = fmt.Sprintf("chore: promote %s to version %s", app, versionName)
-	o.CommitMessage = comment
-	if o.AddChangelog != "" {
-		changelog, err := os.ReadFile(o.AddChangelog)
+	if approval := releaseInfo.Approval; approval != nil {
+		notes += fmt.Sprintf("\n\nThis Pull Request requires %s before it is merged", approval.String())
+	}
+
+	changelog, err := appsChangelog(apps)
+	if err != nil {
+		return err
+	}
+	if changelog != "" {
+		g.PullRequest.CommitChangelog = changelog
+	}
+
+	g.PullRequest.CommitTitle = "chore: promote " + appsTitle(apps)
+	g.PullRequest.CommitMessage = comment + notes
+	g.PullRequest.FullCommitMessage = ""
+	if g.PromoteConfig != nil && g.PromoteConfig.Spec.PullRequest != nil {
+		err = g.RenderPullRequestTemplates(envs, g.PromoteConfig.Spec.PullRequest, changelog, notes)
 		if err != nil {
-			return fmt.Errorf("failed to read changelog file %s: %w", o.AddChangelog, err)
+			return err
 		}
-		o.CommitChangelog = string(changelog)
 	}
 
 	envDir := ""
-	if o.CloneDir != "" {
-		envDir = o.CloneDir
+	if g.CloneDir != "" {
+		envDir = g.CloneDir
 	}
 
-	o.Function = func() error {
-		dir := o.OutDir
+	g.PullRequest.Function = g.promoteFunction(envs, apps)
 
+	g.PullRequest.PullRequestNumber = 0
+	if releaseInfo.PullRequestInfo != nil {
+		g.PullRequest.PullRequestNumber = releaseInfo.PullRequestInfo.Number
+	}
+	gitURL, err := g.EnvironmentGitURL(envs[0])
+	if err != nil {
+		return err
+	}
+	autoMerge := g.AutoMerge
+	if draftPR {
+		autoMerge = false
+	}
+	info, err := g.PullRequest.Create(gitURL, envDir, labels, autoMerge)
+	releaseInfo.PullRequestInfo = info
+	if err != nil {
+		return err
+	}
+	err = g.RequestReviews(info, releaseInfo.Approval)
+	if err != nil {
+		g.log.Warnf("%s", err.Error())
+	}
+	if g.CloseSuperseded {
+		err = g.CloseSupersededPullRequests(info, g.promoteLabels(envs))
+		if err != nil {
+			g.log.Warnf("%s", err.Error())
+		}
+	}
+	return nil
+}
+
+// promoteFunction returns the function which applies the promotion rules of the apps to the environments in the
+// clone of the environment repository
+func (g *GroupContext) promoteFunction(envs []*jxcore.EnvironmentConfig, apps []App) func() error {
+	return func() error {
+		dir := g.PullRequest.OutDir
+
+		var prePush []v1alpha1.HookCommand
+		var prePushContext *hooks.TemplateContext
 		for _, env := range envs {
 			promoteNS := EnvironmentNamespace(env)
 			promoteConfig, _, err := promoteconfig.Discover(dir, promoteNS)
@@ -78,72 +121,169 @@ func (o *Options) PromoteViaPullRequest(envs []*jxcore.EnvironmentConfig, releas
 				return fmt.Errorf("failed to discover the PromoteConfig in dir %s: %w", dir, err)
 			}
 
-			r := &rules.PromoteRule{
-				TemplateContext: rules.TemplateContext{
-					GitURL:            "",
-					Version:           o.Version,
-					AppName:           o.Application,
-					ChartAlias:        o.Alias,
-					Namespace:         o.Namespace,
-					HelmRepositoryURL: o.HelmRepositoryURL,
-					ReleaseName:       o.ReleaseName,
-				},
-				Dir:           dir,
-				Config:        *promoteConfig,
-				DevEnvContext: &o.DevEnvContext,
+			hookContext := g.hookContext(env, dir, apps)
+			promoteHooks := promoteConfig.Spec.Hooks
+			if promoteHooks == nil {
+				promoteHooks = &v1alpha1.Hooks{}
+			}
+			err = hooks.Run(g.CommandRunner, hooks.PreRule, promoteHooks.PreRule, hookContext)
+			if err != nil {
+				return err
 			}
 
-			// lets check if we need the apps git URL
-			if promoteConfig.Spec.FileRule != nil || promoteConfig.Spec.KptRule != nil {
-				if o.AppGitURL == "" {
-					_, gitConf, err := gitclient.FindGitConfigDir("")
-					if err != nil {
-						return fmt.Errorf("failed to find git config dir: %w", err)
-					}
-					o.AppGitURL, err = gitconfig.DiscoverUpstreamGitURL(gitConf, true)
-					if err != nil {
-						return fmt.Errorf("failed to discover application git URL: %w", err)
-					}
-					if o.AppGitURL == "" {
-						return fmt.Errorf("could not to discover application git URL")
+			for i := range apps {
+				a := &apps[i]
+				helmRepositoryURL := a.HelmRepositoryURL
+				if helmRepositoryURL == "" {
+					helmRepositoryURL = g.HelmRepositoryURL
+				}
+				releaseName := a.ReleaseName
+				if releaseName == "" {
+					releaseName = a.Name
+				}
+				r := &rules.PromoteRule{
+					TemplateContext: rules.TemplateContext{
+						GitURL:            "",
+						Version:           a.Version,
+						AppName:           a.Name,
+						ChartAlias:        a.Alias,
+						Namespace:         g.Namespace,
+						HelmRepositoryURL: helmRepositoryURL,
+						ReleaseName:       releaseName,
+					},
+					Dir:           dir,
+					Config:        *promoteConfig,
+					DevEnvContext: &g.DevEnvContext,
+				}
+
+				// lets check if we need the apps git URL
+				if promoteConfig.Spec.FileRule != nil || promoteConfig.Spec.KptRule != nil {
+					if a.GitURL == "" {
+						if len(g.Apps) > 0 {
+							return fmt.Errorf("no gitURL specified for app %s which is required for file and kpt rules", a.Name)
+						}
+						if g.AppGitURL == "" {
+							_, gitConf, err := gitclient.FindGitConfigDir("")
+							if err != nil {
+								return fmt.Errorf("failed to find git config dir: %w", err)
+							}
+							g.AppGitURL, err = gitconfig.DiscoverUpstreamGitURL(gitConf, true)
+							if err != nil {
+								return fmt.Errorf("failed to discover application git URL: %w", err)
+							}
+							if g.AppGitURL == "" {
+								return fmt.Errorf("could not to discover application git URL")
+							}
+						}
+						a.GitURL = g.AppGitURL
 					}
+					r.GitURL = a.GitURL
 				}
-				r.GitURL = o.AppGitURL
-			}
 
-			fn := factory.NewFunction(r)
-			if fn == nil {
-				return fmt.Errorf("could not create rule function ")
+				fn := factory.NewFunction(r)
+				if fn == nil {
+					return fmt.Errorf("could not create rule function ")
+				}
+				err = fn(r)
+				if err != nil {
+					return fmt.Errorf("failed to promote %s to %s: %w", a.Name, env.Key, err)
+				}
 			}
-			err = fn(r)
+
+			err = hooks.Run(g.CommandRunner, hooks.PostRule, promoteHooks.PostRule, hookContext)
 			if err != nil {
-				return fmt.Errorf("failed to promote to %s: %w", env.Key, err)
+				return err
+			}
+			if len(promoteHooks.PrePush) > 0 {
+				prePush = promoteHooks.PrePush
+				prePushContext = hookContext
 			}
 		}
-		return nil
+		return hooks.Run(g.CommandRunner, hooks.PrePush, prePush, prePushContext)
 	}
+}
 
-	if releaseInfo.PullRequestInfo != nil {
-		o.PullRequestNumber = releaseInfo.PullRequestInfo.Number
+// hookContext returns the template context of the hooks run in the dir when promoting the apps to the environment
+func (g *GroupContext) hookContext(env *jxcore.EnvironmentConfig, dir string, apps []App) *hooks.TemplateContext {
+	ctx := &hooks.TemplateContext{
+		TemplateContext: rules.TemplateContext{
+			GitURL:            g.AppGitURL,
+			Version:           g.Version,
+			AppName:           g.Application,
+			Namespace:         g.Namespace,
+			HelmRepositoryURL: g.HelmRepositoryURL,
+			ReleaseName:       g.ReleaseName,
+		},
+		Application: g.Application,
+		Environment: env.Key,
+		Dir:         dir,
 	}
-	env := envs[0]
+	if len(apps) == 1 {
+		a := &apps[0]
+		ctx.AppName = a.Name
+		ctx.Application = a.Name
+		ctx.Version = a.Version
+		ctx.ChartAlias = a.Alias
+		if a.GitURL != "" {
+			ctx.GitURL = a.GitURL
+		}
+		if a.HelmRepositoryURL != "" {
+			ctx.HelmRepositoryURL = a.HelmRepositoryURL
+		}
+		if a.ReleaseName != "" {
+			ctx.ReleaseName = a.ReleaseName
+		}
+	}
+	return ctx
+}
+
+// promoteLabels returns the labels identifying the Pull Request promoting the applications to the environments
+func (g *GroupContext) promoteLabels(envs []*jxcore.EnvironmentConfig) []string {
+	var labels []string
+
+	// TODO: Support more labels. I'm thinking owner...
+	for _, env := range envs {
+		labels = append(labels, "env/"+env.Key)
+	}
+
+	apps := g.PromoteApps()
+	for i := range apps {
+		var dependencyLabel = "dependency/" + g.FullAppName(apps[i].Name)
+
+		if len(dependencyLabel) > 49 {
+			dependencyLabel = dependencyLabel[:49]
+		}
+		labels = append(labels, dependencyLabel)
+	}
+	return labels
+}
+
+// pullRequestLabels returns the labels of a new Pull Request holding it from merging if it is a draft or the
+// environments are frozen
+func (o *Options) pullRequestLabels(labels []string, freeze *ActiveFreeze, draftPR bool) []string {
+	answer := append([]string(nil), labels...)
+	if draftPR {
+		answer = append(answer, "do-not-merge/hold")
+	}
+	if freeze != nil && !o.OverrideFreeze {
+		answer = append(answer, LabelFreeze)
+	}
+	return answer
+}
+
+// EnvironmentGitURL returns the git URL of the repository the Pull Request promoting to the environment is created on
+func (o *Options) EnvironmentGitURL(env *jxcore.EnvironmentConfig) (string, error) {
 	gitURL := requirements.EnvironmentGitURL(o.DevEnvContext.Requirements, env.Key)
 	if gitURL == "" {
 		if env.RemoteCluster {
-			return fmt.Errorf("no git URL for remote cluster %s", env.Key)
+			return "", fmt.Errorf("no git URL for remote cluster %s", env.Key)
 		}
 
 		// lets default to the git repository for the dev environment for local clusters
 		gitURL = requirements.EnvironmentGitURL(o.DevEnvContext.Requirements, "dev")
 		if gitURL == "" {
-			return fmt.Errorf("no git URL for dev environment")
+			return "", fmt.Errorf("no git URL for dev environment")
 		}
 	}
-	autoMerge := o.AutoMerge
-	if draftPR {
-		autoMerge = false
-	}
-	info, err := o.Create(gitURL, envDir, labels, autoMerge)
-	releaseInfo.PullRequestInfo = info
-	return err
+	return gitURL, nil
 }
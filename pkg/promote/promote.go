@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/requirements"
@@ -17,7 +18,12 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
 
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/git/setup"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
 	"github.com/jenkins-x-plugins/jx-promote/pkg/environments"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/notify"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/signing"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/tracing"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/builds"
@@ -49,6 +55,8 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -59,6 +67,10 @@ const (
 	optionTimeout             = "timeout"
 	optionPullRequestPollTime = "pull-request-poll-time"
 	optionInteractive         = "interactive"
+	optionSkipSoak            = "skip-soak"
+	optionOverrideFreeze      = "override-freeze"
+	optionReason              = "reason"
+	optionSigningFormat       = "signing-format"
 
 	// DefaultChartRepo default URL for charts repository
 	DefaultChartRepo = "http://jenkins-x-chartmuseum:8080"
@@ -91,6 +103,7 @@ type Options struct {
 	NoWaitAfterMerge    bool
 	NoGroupPullRequest  bool
 	IgnoreLocalFiles    bool
+	SkipSoak            bool
 	DisableGitConfig    bool //  to disable git init in unit tests
 	Interactive         bool
 	Timeout             string
@@ -98,6 +111,31 @@ type Options struct {
 	Filter              string
 	Alias               string
 	AddChangelog        string
+	AppsFile            string
+	VersionConstraint   string
+	AllowPrerelease     bool
+	OverrideFreeze      bool
+	OverrideReason      string
+	Parallel            int
+	Resume              bool
+	CDEvents            bool
+	StateFile           string
+	OutputFormat        string
+	OutputFile          string
+	NotifyWebhooks      []string
+	NotifySlack         []string
+	NotifyTeams         []string
+	EventsSink          string
+	MergeMethod         string
+	BranchNameTemplate  string
+	CloseSuperseded     bool
+	NativeAutoMerge     bool
+	WebhookListen       string
+	WebhookSecret       string
+	WebhookPollTime     string
+	MaxRebases          int
+	RequiredChecks      []string
+	Apps                []App
 
 	KubeClient kubernetes.Interface
 	JXClient   versioned.Interface
@@ -110,8 +148,15 @@ type Options struct {
 	PullRequestPollDuration *time.Duration
 	Activities              typev1.PipelineActivityInterface
 	GitInfo                 *giturl.GitRepository
-	releaseResource         *v1.Release
-	ReleaseInfo             *ReleaseInfo
+	PromoteConfig           *v1alpha1.Promote
+	latestVersion           bool
+	activityLock            *sync.Mutex
+	concurrent              bool
+	state                   *stateStore
+	notifiers               []*notify.Notifier
+	eventsSink              *notify.CloudEventSink
+	WebhookPollDuration     time.Duration
+	webhooks                *WebhookListener
 
 	// Used for testing
 	CloneDir string
@@ -122,6 +167,11 @@ type ReleaseInfo struct {
 	FullAppName     string
 	Version         string
 	PullRequestInfo *scm.PullRequest
+	Freeze          *ActiveFreeze
+	Approval        *ApprovalPolicy
+	ApplicationURL  string
+	Environments    []*jxcore.EnvironmentConfig
+	PushCommitSHA   string
 }
 
 var (
@@ -147,6 +197,9 @@ var (
 		# To promote a postgres chart using an alias
 		jx promote -f postgres --alias mydb
 
+		# To preview the Pull Requests a promotion would create
+		jx promote plan --version 1.2.3 --all
+
 		# To create or update a Preview Environment please see the 'jx preview' command if you are inside a git clone of a repo
 		jx preview
 	`)
@@ -160,6 +213,8 @@ func NewCmdPromote() (*cobra.Command, *Options) {
 		Short:   "Promotes a version of an application to an Environment",
 		Long:    promoteLong,
 		Example: promoteExample,
+		// lets allow the application name argument alongside the subcommands
+		Args: cobra.ArbitraryArgs,
 		Run: func(_ *cobra.Command, args []string) {
 			opts.Args = args
 			err := opts.Run()
@@ -167,26 +222,33 @@ func NewCmdPromote() (*cobra.Command, *Options) {
 		},
 	}
 
-	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "The Namespace to promote to")
-	cmd.Flags().StringArrayVarP(&opts.Environments, optionEnvironment, "e", nil, "The environment(s) to promote to")
-	cmd.Flags().BoolVarP(&opts.AllAutomatic, "all-auto", "", false, "Promote to all automatic environments in order")
-	cmd.Flags().BoolVarP(&opts.All, "all", "", false, "Promote to all automatic and manual environments in order using a draft PR for manual promotion environments. Implies batch mode.")
-	cmd.Flags().BoolVarP(&opts.BatchMode, "batch-mode", "b", false, "Enables batch mode which avoids prompting for user input")
-	cmd.Flags().BoolVarP(&opts.Interactive, optionInteractive, "", false, "Enables interactive mode")
-
+	opts.addEnvironmentOptions(cmd)
 	opts.AddOptions(cmd)
 	return cmd, opts
 }
 
+// addEnvironmentOptions adds the options selecting the environments to promote to
+func (o *Options) addEnvironmentOptions(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "The Namespace to promote to")
+	cmd.Flags().StringArrayVarP(&o.Environments, optionEnvironment, "e", nil, "The environment(s) to promote to")
+	cmd.Flags().BoolVarP(&o.AllAutomatic, "all-auto", "", false, "Promote to all automatic environments in order")
+	cmd.Flags().BoolVarP(&o.All, "all", "", false, "Promote to all automatic and manual environments in order using a draft PR for manual promotion environments. Implies batch mode.")
+	cmd.Flags().BoolVarP(&o.BatchMode, "batch-mode", "b", false, "Enables batch mode which avoids prompting for user input")
+	cmd.Flags().BoolVarP(&o.Interactive, optionInteractive, "", false, "Enables interactive mode")
+}
+
 // AddOptions adds command level options to `promote`
 func (o *Options) AddOptions(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&o.Application, optionApplication, "a", "", "The Application to promote")
+	cmd.Flags().VarP(&appFlag{o: o}, optionApplication, "a", "The Application to promote. Specify 'name=version' multiple times to promote several applications in a single Pull Request")
+	cmd.Flags().StringVarP(&o.AppsFile, "from-file", "", "", "A YAML file listing the applications to promote in a single Pull Request")
 	cmd.Flags().StringVarP(&o.AppGitURL, "app-git-url", "", "", "The Git URL of the application being promoted. Only required if using file or kpt rules")
 	cmd.Flags().StringVarP(&o.Filter, "filter", "f", "", "The search filter to find charts to promote")
 	cmd.Flags().StringVarP(&o.Alias, "alias", "", "", "The optional alias used in the 'requirements.yaml' file")
 	cmd.Flags().StringVarP(&o.Pipeline, "pipeline", "", "", "The Pipeline string in the form 'folderName/repoName/branch' which is used to update the PipelineActivity. If not specified its defaulted from  the '$BUILD_NUMBER' environment variable")
 	cmd.Flags().StringVarP(&o.Build, "build", "", "", "The Build number which is used to update the PipelineActivity. If not specified its defaulted from  the '$BUILD_NUMBER' environment variable")
 	cmd.Flags().StringVarP(&o.Version, "version", "v", "", "The Version to promote. If no version is specified it defaults to $VERSION which is usually populated in a pipeline. If no value can be found you will be prompted to pick the version")
+	cmd.Flags().StringVarP(&o.VersionConstraint, "version-constraint", "", "", "The semantic version constraint such as '~1.4' used to pick the latest version to promote if no version is specified")
+	cmd.Flags().BoolVarP(&o.AllowPrerelease, "allow-prerelease", "", false, "Allows pre-release versions to be picked as the latest version to promote")
 	cmd.Flags().StringVarP(&o.VersionFile, "version-file", "", "", "the file to load the version from if not specified directly or via a $VERSION environment variable. Defaults to VERSION in the current dir")
 	cmd.Flags().StringVarP(&o.AddChangelog, "add-changelog", "c", "", "a file to take a changelog from to add to the pullr equest body. Typically a file generated by jx changelog.")
 	cmd.Flags().StringVarP(&o.ChangelogSeparator, "changelog-separator", "", os.Getenv("CHANGELOG_SEPARATOR"), "the separator to use between commit message and changelog in the pull request body. Default to ----- or if set the CHANGELOG_SEPARATOR environment variable")
@@ -199,12 +261,36 @@ func (o *Options) AddOptions(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.DevEnvContext.GitToken, "git-token", "", "", "Git token used to clone the development environment. If not specified its loaded from the git credentials file")
 
 	cmd.Flags().BoolVarP(&o.NoHelmUpdate, "no-helm-update", "", false, "Allows the 'helm repo update' command if you are sure your local helm cache is up to date with the version you wish to promote")
+	cmd.Flags().StringVarP(&o.MergeMethod, optionMergeMethod, "", "", "The method used to merge Pull Requests: "+strings.Join(MergeMethods, ", ")+". Defaults to the git provider default")
+	cmd.Flags().StringVarP(&o.BranchNameTemplate, optionBranchName, "", "", "The go template of the name of the branch of promotion Pull Requests such as 'promote/{{ .Environment }}/{{ .Application }}-{{ .Version }}'. The branch is also used to find an existing Pull Request to update. Defaults to the pullRequest.branchName of the promote configuration or a generated name")
+	cmd.Flags().StringVarP(&o.SigningKey, "signing-key", "", "", "The path or contents of the SSH or GPG key used to sign the promotion commits. Defaults to the "+signing.EnvSigningKey+" environment variable")
+	cmd.Flags().StringVarP(&o.SigningFormat, optionSigningFormat, "", "", "The format of the commit signatures: "+strings.Join(signing.Formats, ", ")+". Detected from the signing key if it is the contents or file of a PGP or SSH key")
+	cmd.Flags().BoolVarP(&o.CloseSuperseded, "close-superseded", "", false, "Closes any other open Pull Requests promoting the same applications to the same environments after creating a Pull Request, commenting with a link to the new Pull Request and deleting their branches")
+	cmd.Flags().StringArrayVarP(&o.RequiredChecks, optionRequiredStatusCheck, "", nil, "The context of a commit status or name of a check run which must succeed before a Pull Request is merged. Can be specified multiple times. Defaults to all of the statuses and check runs of the last commit")
+	cmd.Flags().IntVarP(&o.MaxRebases, "max-rebases", "", DefaultMaxRebases, "The maximum number of times the branch of a Pull Request which cannot be merged is regenerated on the latest base branch")
 	cmd.Flags().BoolVarP(&o.NoMergePullRequest, "no-merge", "", false, "Disables automatic merge of promote Pull Requests")
+	cmd.Flags().StringVarP(&o.WebhookListen, optionWebhookListen, "", "", "The address such as ':8080' to listen on for the webhooks of the git provider. If specified Pull Requests are checked when a webhook of their repository is received rather than every --"+optionPullRequestPollTime+". Only the webhooks of the git server of the first Pull Request are received. The Pull Requests on other git servers are polled")
+	cmd.Flags().StringVarP(&o.WebhookSecret, optionWebhookSecret, "", "", "The HMAC token used to validate the webhooks. Defaults to the "+EnvWebhookSecret+" environment variable")
+	cmd.Flags().StringVarP(&o.WebhookPollTime, optionWebhookPollTime, "", DefaultWebhookPollTime.String(), "Poll time when waiting for a Pull Request to merge if no webhooks of its repository are received")
+	cmd.Flags().BoolVarP(&o.NativeAutoMerge, "native-auto-merge", "", false, "Hands promote Pull Requests to the auto merge or merge queue of the git provider and waits for them to merge. Falls back to merging when the checks pass if the git provider does not support it")
 
 	cmd.Flags().BoolVarP(&o.NoPoll, "no-poll", "", false, "Disables polling for Pull Request or Pipeline status")
 	cmd.Flags().BoolVarP(&o.NoGroupPullRequest, "no-pr-group", "", false, "Disables grouping Auto promotions to different Environments in the same git repository within a single Pull Request which causes them to use separate Pull Requests")
 	cmd.Flags().BoolVarP(&o.NoWaitAfterMerge, "no-wait", "", false, "Disables waiting for completing promotion after the Pull request is merged")
 	cmd.Flags().BoolVarP(&o.IgnoreLocalFiles, "ignore-local-file", "", false, "Ignores the local file system when deducing the Git repository")
+	cmd.Flags().IntVarP(&o.Parallel, "parallel", "", 1, "The maximum number of groups of environments to promote to concurrently. Groups are promoted concurrently unless they are ordered by needs or follow a group with a minSoakTime in the promote configuration")
+	cmd.Flags().BoolVarP(&o.Resume, "resume", "", false, "Persists the state of the promotion in a ConfigMap so that if it is restarted it resumes a previous promotion of the same version which did not complete by waiting for its existing Pull Requests")
+	cmd.Flags().StringVarP(&o.StateFile, "state-file", "", "", "The file to persist the state of the promotion to so that it can be resumed. If not specified the state is persisted in a ConfigMap in the current namespace when --resume is specified")
+	cmd.Flags().StringVarP(&o.OutputFormat, optionOutput, "o", "", "The format to output the result of the promotion in. Either 'json' or 'yaml'")
+	cmd.Flags().StringVarP(&o.OutputFile, "output-file", "", "", "The file to write the result of the promotion to. Defaults to the standard output")
+	cmd.Flags().StringArrayVarP(&o.NotifyWebhooks, "notify-webhook", "", nil, "The URL of a webhook to post promotion events to as JSON")
+	cmd.Flags().StringArrayVarP(&o.NotifySlack, "notify-slack", "", nil, "The URL of a Slack compatible incoming webhook to post promotion events to")
+	cmd.Flags().StringArrayVarP(&o.NotifyTeams, "notify-teams", "", nil, "The URL of a Microsoft Teams incoming webhook to post promotion events to")
+	cmd.Flags().StringVarP(&o.EventsSink, "events-sink", "", "", "The URL to send CloudEvents of the promotion lifecycle to. Defaults to $K_SINK")
+	cmd.Flags().BoolVarP(&o.CDEvents, "cdevents", "", false, "Sends the CloudEvents to the --events-sink using the vocabulary of the CDEvents specification")
+	cmd.Flags().BoolVarP(&o.SkipSoak, optionSkipSoak, "", false, "Skips waiting for the minSoakTime of the previously promoted environments before promoting to the next environments")
+	cmd.Flags().BoolVarP(&o.OverrideFreeze, optionOverrideFreeze, "", false, "Overrides any active change freeze windows of the environments. Requires --"+optionReason)
+	cmd.Flags().StringVarP(&o.OverrideReason, optionReason, "", "", "The reason for overriding a change freeze which is recorded in the Pull Request")
 	cmd.Flags().BoolVarP(&o.AutoMerge, "auto-merge", "", false, "If enabled add the 'updatebot' label to tell lighthouse to eagerly merge. Usually the Pull Request pipeline will add this label during the Pull Request pipeline after any extra generation/commits have been done and the PR is valid")
 }
 
@@ -319,61 +405,61 @@ func (o *Options) Validate() error {
 	if o.ChangelogSeparator == "" {
 		o.ChangelogSeparator = "-----"
 	}
-	return nil
+	if o.OverrideFreeze && o.OverrideReason == "" {
+		return options.MissingOption(optionReason)
+	}
+	err = ValidateMergeMethod(o.MergeMethod)
+	if err != nil {
+		return options.InvalidOptionf(optionMergeMethod, o.MergeMethod, "%s", err.Error())
+	}
+	if o.SigningKey == "" {
+		o.SigningKey = os.Getenv(signing.EnvSigningKey)
+	}
+	if o.WebhookSecret == "" {
+		o.WebhookSecret = os.Getenv(EnvWebhookSecret)
+	}
+	if o.WebhookListen != "" && o.WebhookSecret == "" {
+		log.Logger().Warnf("webhooks received on %s are not validated as no --%s or %s environment variable is specified", o.WebhookListen, optionWebhookSecret, EnvWebhookSecret)
+	}
+	err = signing.ValidateFormat(o.SigningFormat)
+	if err != nil {
+		return options.InvalidOptionf(optionSigningFormat, o.SigningFormat, "%s", err.Error())
+	}
+	if o.SigningKey != "" && o.SigningFormat == "" {
+		o.SigningFormat, err = signing.DefaultFormat(o.SigningKey)
+		if err != nil {
+			return fmt.Errorf("%w: %s", options.MissingOption(optionSigningFormat), err.Error())
+		}
+	}
+	return o.ValidateOutput()
 }
 
 // Run implements this command
 func (o *Options) Run() error {
+	ctx, shutdown, err := tracing.Init(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to initialise tracing: %w", err)
+	}
+	defer shutdown()
+
+	ctx, span := tracing.Start(ctx, "promote")
+	o.Context = ctx
+	err = o.run()
+	tracing.End(span, err)
+	return err
+}
+
+func (o *Options) run() error {
 	err := o.Validate()
 	if err != nil {
 		return fmt.Errorf("failed to validate options: %w", err)
 	}
 
-	// TODO move to validate
-	err = o.EnsureApplicationNameIsDefined(o.SearchForChart, o.DiscoverAppName, o.ChooseChart)
+	err = o.ResolveApplications()
 	if err != nil {
 		return err
 	}
-
-	if o.Version == "" {
-		exists, err := files.FileExists(o.VersionFile)
-		if err != nil {
-			return fmt.Errorf("failed to check for file %s: %w", o.VersionFile, err)
-		}
-		if exists {
-			data, err := os.ReadFile(o.VersionFile)
-			if err != nil {
-				return fmt.Errorf("failed to read version file %s: %w", o.VersionFile, err)
-			}
-			o.Version = strings.TrimSpace(string(data))
-		}
-		if o.Version != "" {
-			log.Logger().Infof("defaulting to the version %s from file %s", termcolor.ColorInfo(o.Version), termcolor.ColorInfo(o.VersionFile))
-		}
-		if o.Version == "" {
-			o.Version = os.Getenv("VERSION")
-			if o.Version != "" {
-				log.Logger().Infof("defaulting to the version %s from $VERSION", termcolor.ColorInfo(o.Version))
-			}
-		}
-	}
-	if o.Version == "" && o.Application != "" {
-		if o.Interactive {
-			versions, err := o.getAllVersions(o.Application)
-			if err != nil {
-				return fmt.Errorf("failed to get app versions: %w", err)
-			}
-			o.Version, err = o.Input.PickNameWithDefault(versions, "Pick version:", "", "please select a version")
-			if err != nil {
-				return fmt.Errorf("failed to pick a version: %w", err)
-			}
-		} else {
-			o.Version, err = o.findLatestVersion(o.Application)
-			if err != nil {
-				return fmt.Errorf("failed to find latest version of app %s: %w", o.Application, err)
-			}
-		}
-	}
+	trace.SpanFromContext(o.Context).SetAttributes(attribute.String("promote.application", o.Application), attribute.String("promote.version", o.Version))
 
 	ns := o.Namespace
 	if ns == "" {
@@ -384,11 +470,23 @@ func (o *Options) Run() error {
 		o.GitClient = cli.NewCLIClient("", o.CommandRunner)
 	}
 
+	_, span := tracing.Start(o.Context, "lazy-load")
 	err = o.DevEnvContext.LazyLoad(o.GitClient, o.JXClient, o.Namespace, o.Git(), o.Dir)
+	tracing.End(span, err)
 	if err != nil {
 		return fmt.Errorf("failed to lazy load the EnvironmentContext: %w", err)
 	}
 
+	err = o.loadPromoteConfig()
+	if err != nil {
+		return err
+	}
+
+	err = o.LoadNotifiers()
+	if err != nil {
+		return err
+	}
+
 	if kube.IsInCluster() && !o.DisableGitConfig {
 		err = o.InitGitConfigAndUser()
 		if err != nil {
@@ -402,19 +500,9 @@ func (o *Options) Run() error {
 			return fmt.Errorf("failed to resolve helm repository URL: %w", err)
 		}
 	}
-	if o.Interactive || !(len(o.Environments) != 0 || o.All || o.AllAutomatic || o.BatchMode) { //nolint:staticcheck
-		var names []string
-		envs := o.DevEnvContext.Requirements.Environments
-		for i := range envs {
-			env := &envs[i]
-			if envIsPermanent(env) {
-				names = append(names, env.Key)
-			}
-		}
-		o.Environments, err = o.Input.SelectNames(names, "Pick environment(s):", o.All, "please select one or many environments")
-		if err != nil {
-			return fmt.Errorf("failed to pick an Environment name: %w", err)
-		}
+	pred, err := o.EnvironmentPredicate()
+	if err != nil {
+		return err
 	}
 
 	if o.PullRequestPollTime != "" {
@@ -424,6 +512,16 @@ func (o *Options) Run() error {
 		}
 		o.PullRequestPollDuration = &duration
 	}
+	if o.WebhookListen != "" {
+		if o.WebhookPollTime != "" {
+			o.WebhookPollDuration, err = time.ParseDuration(o.WebhookPollTime)
+			if err != nil {
+				return fmt.Errorf("invalid duration format %s for option --%s: %s", o.WebhookPollTime, optionWebhookPollTime, err)
+			}
+		}
+		o.webhooks = NewWebhookListener(o.WebhookSecret)
+		defer o.webhooks.Close()
+	}
 	if o.Timeout != "" {
 		duration, err := time.ParseDuration(o.Timeout)
 		if err != nil {
@@ -442,23 +540,125 @@ func (o *Options) Run() error {
 		o.ReleaseName = o.Application
 	}
 
+	err = o.LoadState()
+	if err != nil {
+		return fmt.Errorf("failed to load the promotion state: %w", err)
+	}
+
+	return o.PromoteAll(pred)
+}
+
+// ResolveApplications resolves the names and versions of the applications to promote
+func (o *Options) ResolveApplications() error {
+	err := o.LoadApps()
+	if err != nil {
+		return fmt.Errorf("failed to load the applications to promote: %w", err)
+	}
+	if len(o.Apps) > 0 {
+		return o.resolveAppVersions()
+	}
+	// TODO move to validate
+	err = o.EnsureApplicationNameIsDefined(o.SearchForChart, o.DiscoverAppName, o.ChooseChart)
+	if err != nil {
+		return err
+	}
+	return o.resolveVersion()
+}
+
+// loadPromoteConfig loads the promote configuration of the application if not already loaded
+func (o *Options) loadPromoteConfig() error {
+	if o.PromoteConfig != nil {
+		return nil
+	}
+	var err error
+	o.PromoteConfig, _, err = promoteconfig.LoadPromote(o.Dir, false)
+	if err != nil {
+		return fmt.Errorf("failed to load promote configuration: %w", err)
+	}
+	return nil
+}
+
+// EnvironmentPredicate returns the predicate matching the environments to promote to, picking the environments if
+// running interactively
+func (o *Options) EnvironmentPredicate() (func(*jxcore.EnvironmentConfig) bool, error) {
+	if o.Interactive || !(len(o.Environments) != 0 || o.All || o.AllAutomatic || o.BatchMode) { //nolint:staticcheck
+		var names []string
+		envs := o.DevEnvContext.Requirements.Environments
+		for i := range envs {
+			env := &envs[i]
+			if envIsPermanent(env) {
+				names = append(names, env.Key)
+			}
+		}
+		var err error
+		o.Environments, err = o.Input.SelectNames(names, "Pick environment(s):", o.All, "please select one or many environments")
+		if err != nil {
+			return nil, fmt.Errorf("failed to pick an Environment name: %w", err)
+		}
+	}
+
 	if len(o.Environments) > 0 {
-		return o.PromoteAll(func(env *jxcore.EnvironmentConfig) bool {
+		return func(env *jxcore.EnvironmentConfig) bool {
 			return Contains(o.Environments, env.Key)
-		})
+		}, nil
 	}
-
 	if o.All {
-		return o.PromoteAll(func(env *jxcore.EnvironmentConfig) bool {
+		return func(env *jxcore.EnvironmentConfig) bool {
 			return (env.PromotionStrategy == v1.PromotionStrategyTypeAutomatic || env.PromotionStrategy == v1.PromotionStrategyTypeManual) && envIsPermanent(env)
-		})
+		}, nil
 	}
 	if o.AllAutomatic {
-		return o.PromoteAll(func(env *jxcore.EnvironmentConfig) bool {
+		return func(env *jxcore.EnvironmentConfig) bool {
 			return env.PromotionStrategy == v1.PromotionStrategyTypeAutomatic && envIsPermanent(env)
-		})
+		}, nil
 	}
-	return fmt.Errorf("in bach mode one option needs to specified of: --%s, --all and --all-auto", optionEnvironment)
+	return nil, fmt.Errorf("in bach mode one option needs to specified of: --%s, --all and --all-auto", optionEnvironment)
+}
+
+// resolveVersion defaults the version to promote from the version file, $VERSION or the helm repositories
+func (o *Options) resolveVersion() error {
+	if o.Version == "" {
+		exists, err := files.FileExists(o.VersionFile)
+		if err != nil {
+			return fmt.Errorf("failed to check for file %s: %w", o.VersionFile, err)
+		}
+		if exists {
+			data, err := os.ReadFile(o.VersionFile)
+			if err != nil {
+				return fmt.Errorf("failed to read version file %s: %w", o.VersionFile, err)
+			}
+			o.Version = strings.TrimSpace(string(data))
+		}
+		if o.Version != "" {
+			log.Logger().Infof("defaulting to the version %s from file %s", termcolor.ColorInfo(o.Version), termcolor.ColorInfo(o.VersionFile))
+		}
+		if o.Version == "" {
+			o.Version = os.Getenv("VERSION")
+			if o.Version != "" {
+				log.Logger().Infof("defaulting to the version %s from $VERSION", termcolor.ColorInfo(o.Version))
+			}
+		}
+	}
+	if o.Version == "" && o.Application != "" {
+		if o.Interactive {
+			versions, err := o.getAllVersions(o.Application)
+			if err != nil {
+				return fmt.Errorf("failed to get app versions: %w", err)
+			}
+			o.Version, err = o.Input.PickNameWithDefault(versions, "Pick version:", "", "please select a version")
+			if err != nil {
+				return fmt.Errorf("failed to pick a version: %w", err)
+			}
+		} else {
+			var err error
+			o.Version, err = o.findLatestVersion(o.Application)
+			if err != nil {
+				return fmt.Errorf("failed to find latest version of app %s: %w", o.Application, err)
+			}
+			o.latestVersion = true
+		}
+	}
+	return nil
 }
 
 func envIsPermanent(env *jxcore.EnvironmentConfig) bool {
@@ -529,6 +729,46 @@ func (o *Options) DefaultChartRepositoryURL() string {
 }
 
 func (o *Options) PromoteAll(pred func(*jxcore.EnvironmentConfig) bool) error {
+	graph, err := o.PromoteGraph(pred)
+	if err != nil {
+		return fmt.Errorf("failed to create the promotion graph: %w", err)
+	}
+	groups := graph.Groups()
+	version := o.Version
+	o.resolveBuildInfo()
+	o.createClients(groups)
+	var results []*GroupResult
+	if o.Parallel > 1 && len(groups) > 1 {
+		o.concurrent = true
+		if o.activityLock == nil {
+			o.activityLock = &sync.Mutex{}
+		}
+	}
+	switch {
+	case graph.HasNeeds():
+		results, err = o.promoteGraph(graph, version)
+		if o.Parallel > 1 {
+			logGroupResults(results)
+		}
+	case o.Parallel > 1 && len(groups) > 1:
+		results, err = o.promoteGroupsInParallel(groups, version)
+		logGroupResults(results)
+	default:
+		results, err = o.promoteGroupsInSequence(groups, version)
+	}
+	err2 := o.WriteOutput(results)
+	if err != nil {
+		return err
+	}
+	if err2 != nil {
+		return err2
+	}
+	return o.clearStateIfCompleted(results)
+}
+
+// PromoteGroups returns the groups of environments matching the predicate in promotion order. Automatic environments
+// sharing a git repository are grouped so that they are promoted via a single Pull Request
+func (o *Options) PromoteGroups(pred func(*jxcore.EnvironmentConfig) bool) [][]*jxcore.EnvironmentConfig {
 	envs := o.DevEnvContext.Requirements.Environments
 	if len(envs) == 0 {
 		log.Logger().Warnf("No Environments have been specified in the requirements")
@@ -558,14 +798,15 @@ func (o *Options) PromoteAll(pred func(*jxcore.EnvironmentConfig) bool) error {
 		}
 	}
 
-	// lets group Auto env promotions together into the same git URL
+	// lets group Auto env promotions together into the same git URL which need the same environments
 	var groups [][]*jxcore.EnvironmentConfig
 	for _, env := range promoteEnvs {
 		if !o.NoGroupPullRequest && env.PromotionStrategy == v1.PromotionStrategyTypeAutomatic {
 			// let's see if the env is has the same url as existing automatic group
 			i := slices.IndexFunc(groups,
 				func(group []*jxcore.EnvironmentConfig) bool {
-					return group[0].GitURL == env.GitURL && group[0].PromotionStrategy == v1.PromotionStrategyTypeAutomatic
+					return group[0].GitURL == env.GitURL && group[0].PromotionStrategy == v1.PromotionStrategyTypeAutomatic &&
+						o.needKeys(group[0]) == o.needKeys(env)
 				})
 			if i >= 0 {
 				groups[i] = append(groups[i], env)
@@ -574,24 +815,7 @@ func (o *Options) PromoteAll(pred func(*jxcore.EnvironmentConfig) bool) error {
 		}
 		groups = append(groups, []*jxcore.EnvironmentConfig{env})
 	}
-	for _, group := range groups {
-		firstEnv := group[0]
-
-		// lets clear the branch name so that we create a new branch for each PR...
-		o.BranchName = ""
-		releaseInfo, err := o.Promote(group, false, o.NoPoll)
-		if err != nil {
-			return err
-		}
-		o.ReleaseInfo = releaseInfo
-		if !o.NoPoll {
-			err = o.WaitForPromotion(firstEnv, releaseInfo)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	return groups
 }
 
 // EnvironmentNamespace returns the namespace for the environment
@@ -603,16 +827,16 @@ func EnvironmentNamespace(env *jxcore.EnvironmentConfig) string {
 	return ns
 }
 
-func (o *Options) Promote(envs []*jxcore.EnvironmentConfig, warnIfAuto, noPoll bool) (*ReleaseInfo, error) {
+func (g *GroupContext) Promote(envs []*jxcore.EnvironmentConfig, warnIfAuto, noPoll bool) (*ReleaseInfo, error) {
 	if len(envs) == 0 {
 		return nil, nil
 	}
-	app := o.Application
+	app := g.Application
 	if app == "" {
-		log.Logger().Warnf("No application name could be detected so cannot promote via Helm. If the detection of the helm chart name is not working consider adding it with the --%s argument on the 'jx promote' command", optionApplication)
+		g.log.Warnf("No application name could be detected so cannot promote via Helm. If the detection of the helm chart name is not working consider adding it with the --%s argument on the 'jx promote' command", optionApplication)
 		return nil, nil
 	}
-	version := o.Version
+	version := g.Version
 	info := termcolor.ColorInfo
 
 	var targetNamespaces []string
@@ -622,40 +846,48 @@ func (o *Options) Promote(envs []*jxcore.EnvironmentConfig, warnIfAuto, noPoll b
 			targetNamespaces = append(targetNamespaces, targetNS)
 		}
 	}
-	if version == "" {
-		log.Logger().Infof("Promoting latest version of app %s to namespace %s", info(app), info(strings.Join(targetNamespaces, " ")))
-	} else {
-		log.Logger().Infof("Promoting app %s version %s to namespace %s", info(app), info(version), info(strings.Join(targetNamespaces, " ")))
+	switch {
+	case len(g.Apps) > 0:
+		g.log.Infof("Promoting apps %s to namespace %s", info(appsTitle(g.Apps)), info(strings.Join(targetNamespaces, " ")))
+	case version == "":
+		g.log.Infof("Promoting latest version of app %s to namespace %s", info(app), info(strings.Join(targetNamespaces, " ")))
+	default:
+		g.log.Infof("Promoting app %s version %s to namespace %s", info(app), info(version), info(strings.Join(targetNamespaces, " ")))
 	}
 
-	fullAppName := app
-	if o.LocalHelmRepoName != "" {
-		fullAppName = o.LocalHelmRepoName + "/" + app
+	fullAppName := g.FullAppName(app)
+	releaseName := g.ReleaseName
+	if releaseName == "" {
+		releaseName = app
 	}
-	if o.ReleaseName == "" {
-		o.ReleaseName = app
+	freeze, err := g.CheckFreeze(envs)
+	if err != nil {
+		return nil, err
 	}
 	releaseInfo := &ReleaseInfo{
-		ReleaseName: o.ReleaseName,
-		FullAppName: fullAppName,
-		Version:     version,
+		ReleaseName:  releaseName,
+		FullAppName:  fullAppName,
+		Version:      version,
+		Freeze:       freeze,
+		Approval:     g.ApprovalPolicy(envs...),
+		Environments: envs,
+	}
+	mode, err := g.GroupPromotionMode(envs, releaseInfo)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, env := range envs {
-		strategy := env.PromotionStrategy
-		if string(strategy) == "" && env.Key == "staging" {
-			// lets default the strategy based if its missing from the Environment
-			strategy = v1.PromotionStrategyTypeAutomatic
-		}
-		draftPR := strategy != v1.PromotionStrategyTypeAutomatic
+		strategy := promotionStrategy(env)
+		draftPR := g.draftPullRequest(env, freeze)
 		targetNS := EnvironmentNamespace(env)
 		if targetNS == "" {
 			return nil, fmt.Errorf("no namespace for environment %s", env.Key)
 		}
 
-		if warnIfAuto && env != nil && strategy == v1.PromotionStrategyTypeAutomatic && !o.BatchMode {
-			log.Logger().Infof("%s", termcolor.ColorWarning(fmt.Sprintf("WARNING: The Environment %s is setup to promote automatically as part of the CI/CD Pipelines.\n", env.Key)))
-			flag, err := o.Input.Confirm("Do you wish to promote anyway? :", false, "usually we do not manually promote to Auto promotion environments")
+		if warnIfAuto && env != nil && strategy == v1.PromotionStrategyTypeAutomatic && !g.BatchMode {
+			g.log.Infof("%s", termcolor.ColorWarning(fmt.Sprintf("WARNING: The Environment %s is setup to promote automatically as part of the CI/CD Pipelines.\n", env.Key)))
+			flag, err := g.Input.Confirm("Do you wish to promote anyway? :", false, "usually we do not manually promote to Auto promotion environments")
 			if err != nil {
 				return nil, fmt.Errorf("failed to confirm promotion: %w", err)
 			}
@@ -664,22 +896,25 @@ func (o *Options) Promote(envs []*jxcore.EnvironmentConfig, warnIfAuto, noPoll b
 			}
 		}
 
-		jxClient := o.JXClient
-		kubeClient := o.KubeClient
-		promoteKey := o.CreatePromoteKey(env)
+		promoteKey := g.CreatePromoteKey(env)
 		if env != nil {
 			if !envIsPermanent(env) {
 				return nil, fmt.Errorf("cannot promote to Environment which is not a permanent Environment")
 			}
-			o.ReusePullRequest = env.ReusePullRequest
+			g.PullRequest.ReusePullRequest = env.ReusePullRequest
 
-			sourceURL := requirements.EnvironmentGitURL(o.DevEnvContext.Requirements, env.Key)
-			if sourceURL == "" && !env.RemoteCluster && o.DevEnvContext.DevEnv != nil {
+			sourceURL := requirements.EnvironmentGitURL(g.DevEnvContext.Requirements, env.Key)
+			if sourceURL == "" && !env.RemoteCluster && g.DevEnvContext.DevEnv != nil {
 				// lets default to the git repository of the dev environment as we are sharing the git repository across multiple namespaces
-				sourceURL = o.DevEnvContext.DevEnv.Spec.Source.URL
+				sourceURL = g.DevEnvContext.DevEnv.Spec.Source.URL
 			}
 			if sourceURL != "" {
-				err := o.PromoteViaPullRequest(envs, releaseInfo, draftPR)
+				g.notify(notify.EventPromotionStarted, env, releaseInfo, promoteKey, nil)
+				if mode == v1alpha1.PromotionModePush {
+					err = g.promoteViaPush(envs, env, releaseInfo, promoteKey)
+					return releaseInfo, err
+				}
+				err = g.PromoteViaPullRequest(envs, releaseInfo, draftPR)
 				if err == nil {
 					startPromotePR := func(a *v1.PipelineActivity, s *v1.PipelineActivityStep, ps *v1.PromoteActivityStep, p *v1.PromotePullRequestStep) error {
 						err = activities.StartPromotionPullRequest(a, s, ps, p)
@@ -704,18 +939,37 @@ func (o *Options) Promote(envs []*jxcore.EnvironmentConfig, warnIfAuto, noPoll b
 						activities.UpdateStatus(a, false, nil)
 						return nil
 					}
-					err = promoteKey.OnPromotePullRequest(kubeClient, jxClient, o.Namespace, startPromotePR)
+					err = g.onPromotePullRequest(promoteKey, startPromotePR)
 					if err != nil {
-						log.Logger().Warnf("Failed to update PipelineActivity: %s", err)
+						g.log.Warnf("Failed to update PipelineActivity: %s", err)
 					}
+					g.notify(notify.EventPullRequestOpened, env, releaseInfo, promoteKey, nil)
 					// lets sleep a little before we try poll for the PR status
 					time.Sleep(waitAfterPullRequestCreated)
+				} else {
+					g.notify(notify.EventPromotionFailed, env, releaseInfo, promoteKey, err)
 				}
 				return releaseInfo, err
 			}
 		}
 	}
-	return nil, fmt.Errorf("no source repository URL available on  environment %s", o.Environments)
+	return nil, fmt.Errorf("no source repository URL available on  environment %s", g.Environments)
+}
+
+// promotionStrategy returns the promotion strategy of the environment defaulting to automatic for staging
+func promotionStrategy(env *jxcore.EnvironmentConfig) v1.PromotionStrategyType {
+	strategy := env.PromotionStrategy
+	if string(strategy) == "" && env.Key == "staging" {
+		// lets default the strategy based if its missing from the Environment
+		strategy = v1.PromotionStrategyTypeAutomatic
+	}
+	return strategy
+}
+
+// draftPullRequest returns true if the Pull Request promoting to the environment should be a draft which is held
+// from merging
+func (o *Options) draftPullRequest(env *jxcore.EnvironmentConfig, freeze *ActiveFreeze) bool {
+	return promotionStrategy(env) != v1.PromotionStrategyTypeAutomatic || (freeze != nil && !o.OverrideFreeze)
 }
 
 // ResolveChartRepositoryURL resolves the current chart repository URL so we can pass it into a remote Environments's
@@ -841,28 +1095,30 @@ func (o *Options) GetTargetNamespace(ns, env string) (string, *jxcore.Environmen
 	return targetNS, envResource, nil
 }
 
-func (o *Options) WaitForPromotion(env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo) error {
-	if o.TimeoutDuration == nil {
-		log.Logger().Infof("No --%s option specified on the 'jx promote' command so not waiting for the promotion to succeed", optionTimeout)
+func (g *GroupContext) WaitForPromotion(env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo) error {
+	if g.TimeoutDuration == nil {
+		g.log.Infof("No --%s option specified on the 'jx promote' command so not waiting for the promotion to succeed", optionTimeout)
 		return nil
 	}
-	if o.PullRequestPollDuration == nil {
-		log.Logger().Infof("No --%s option specified on the 'jx promote' command so not waiting for the promotion to succeed", optionPullRequestPollTime)
+	if g.PullRequestPollDuration == nil {
+		g.log.Infof("No --%s option specified on the 'jx promote' command so not waiting for the promotion to succeed", optionPullRequestPollTime)
 		return nil
 	}
-	duration := *o.TimeoutDuration
+	duration := *g.TimeoutDuration
 	end := time.Now().Add(duration)
 
-	jxClient := o.JXClient
-	kubeClient := o.KubeClient
 	pullRequestInfo := releaseInfo.PullRequestInfo
 	if pullRequestInfo != nil {
-		promoteKey := o.CreatePromoteKey(env)
+		promoteKey := g.CreatePromoteKey(env)
 
-		err := o.waitForGitOpsPullRequest(env, releaseInfo, end, duration, promoteKey)
+		_, span := tracing.Start(g.PullRequest.Context, "wait-for-pull-request", attribute.String("pr.url", pullRequestInfo.Link))
+		err := g.waitForGitOpsPullRequest(env, releaseInfo, end, duration, promoteKey)
+		tracing.End(span, err)
 		if err != nil {
+			g.notifyFailure(env, releaseInfo, promoteKey, err)
+
 			// TODO based on if the PR completed or not fail the PR or the Promote?
-			err2 := promoteKey.OnPromotePullRequest(kubeClient, jxClient, o.Namespace, activities.FailedPromotionPullRequest)
+			err2 := g.onPromotePullRequest(promoteKey, activities.FailedPromotionPullRequest)
 			if err2 != nil {
 				return err2
 			}
@@ -873,20 +1129,27 @@ func (o *Options) WaitForPromotion(env *jxcore.EnvironmentConfig, releaseInfo *R
 }
 
 // TODO This could do with a refactor and some tests...
-func (o *Options) waitForGitOpsPullRequest(env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, end time.Time, duration time.Duration, promoteKey *activities.PromoteStepActivityKey) error {
+func (g *GroupContext) waitForGitOpsPullRequest(env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, end time.Time, duration time.Duration, promoteKey *activities.PromoteStepActivityKey) error {
 	pullRequestInfo := releaseInfo.PullRequestInfo
-	logMergeFailure := false
+	var lastMergeError error
 	logNoMergeCommitSha := false
-	jxClient := o.JXClient
+	waitingForApproval := false
+	nativeAutoMerge := g.NativeAutoMerge && !g.NoMergePullRequest
+	autoMergeEnabled := false
+	rebasePolicy := &RebasePolicy{
+		MaxAttempts:     g.MaxRebases,
+		MaxUnknownPolls: DefaultMaxUnknownMergeablePolls,
+	}
+	jxClient := g.JXClient
 	if jxClient == nil {
 		return fmt.Errorf("no jx client")
 	}
-	kubeClient := o.KubeClient
+	kubeClient := g.KubeClient
 	if kubeClient == nil {
 		return fmt.Errorf("no kube client")
 	}
 
-	scmClient := o.ScmClient
+	scmClient := g.PullRequest.ScmClient
 	if scmClient == nil {
 		return fmt.Errorf("no ScmClient")
 	}
@@ -896,23 +1159,35 @@ func (o *Options) waitForGitOpsPullRequest(env *jxcore.EnvironmentConfig, releas
 	if pullRequestInfo != nil {
 		fullName := pullRequestInfo.Repository().FullName
 		prNumber := pullRequestInfo.Number
+		var events <-chan struct{}
+		if g.webhooks != nil {
+			err := g.webhooks.Start(g.WebhookListen, scmClient)
+			if err != nil {
+				g.log.Warnf("polling Pull Request %s as webhooks cannot be received: %s", pullRequestInfo.Link, err.Error())
+			} else {
+				var unsubscribe func()
+				events, unsubscribe = g.webhooks.Subscribe(fullName)
+				defer unsubscribe()
+			}
+		}
 		for {
 			pr, _, err := scmClient.PullRequests.Find(ctx, fullName, prNumber)
 			if err != nil {
 				return fmt.Errorf("failed to find PR %s %d: %w", fullName, prNumber, err)
 			}
 			if err != nil {
-				log.Logger().Warnf("failed to find PR %s %d: %s", fullName, prNumber, err.Error())
+				g.log.Warnf("failed to find PR %s %d: %s", fullName, prNumber, err.Error())
 			} else {
 				if pr.Merged {
 					if pr.MergeSha == "" {
 						if !logNoMergeCommitSha {
 							logNoMergeCommitSha = true
-							log.Logger().Infof("Pull Request %s is merged but waiting for Merge SHA", termcolor.ColorInfo(pr.Link))
+							g.log.Infof("Pull Request %s is merged but waiting for Merge SHA", termcolor.ColorInfo(pr.Link))
 						}
 					} else {
 						mergeSha := pr.MergeSha
-						log.Logger().Infof("Pull Request %s is merged at sha %s", termcolor.ColorInfo(pr.Link), termcolor.ColorInfo(mergeSha))
+						releaseInfo.PullRequestInfo = pr
+						g.log.Infof("Pull Request %s is merged at sha %s", termcolor.ColorInfo(pr.Link), termcolor.ColorInfo(mergeSha))
 						mergedPR := func(a *v1.PipelineActivity, s *v1.PipelineActivityStep, ps *v1.PromoteActivityStep, p *v1.PromotePullRequestStep) error {
 							err = activities.CompletePromotionPullRequest(a, s, ps, p)
 							if err != nil {
@@ -921,49 +1196,82 @@ func (o *Options) waitForGitOpsPullRequest(env *jxcore.EnvironmentConfig, releas
 							p.MergeCommitSHA = mergeSha
 							return nil
 						}
-						err = promoteKey.OnPromotePullRequest(kubeClient, jxClient, o.Namespace, mergedPR)
+						err = g.onPromotePullRequest(promoteKey, mergedPR)
 						if err != nil {
 							return err
 						}
-						if o.NoWaitAfterMerge {
-							log.Logger().Infof("Pull requests are merged, No wait on promotion to complete")
+						g.notify(notify.EventPullRequestMerged, env, releaseInfo, promoteKey, nil)
+						if g.NoWaitAfterMerge {
+							g.log.Infof("Pull requests are merged, No wait on promotion to complete")
 							return err
 						}
 
-						err = promoteKey.OnPromoteUpdate(kubeClient, jxClient, o.Namespace, activities.StartPromotionUpdate)
+						err = g.onPromoteUpdate(promoteKey, activities.StartPromotionUpdate)
 						if err != nil {
 							return err
 						}
 
-						err = o.CommentOnIssues(env, promoteKey)
+						err = g.CommentOnIssues(env, promoteKey)
+						releaseInfo.ApplicationURL = promoteKey.ApplicationURL
+						if err == nil {
+							err = g.onPromoteUpdate(promoteKey, activities.CompletePromotionUpdate)
+						}
 						if err == nil {
-							err = promoteKey.OnPromoteUpdate(kubeClient, jxClient, o.Namespace, activities.CompletePromotionUpdate)
+							g.notify(notify.EventPromotionSucceeded, env, releaseInfo, promoteKey, nil)
 						}
 						return err
 					}
 				} else {
 					if pr.Closed {
-						log.Logger().Warnf("Pull Request %s is closed", termcolor.ColorInfo(pr.Link))
+						g.log.Warnf("Pull Request %s is closed", termcolor.ColorInfo(pr.Link))
 						return fmt.Errorf("promotion failed as Pull Request %s is closed without merging", pr.Link)
 					}
 
-					prLastCommitSha := o.pullRequestLastCommitSha(pr)
+					envs := releaseInfo.Environments
+					if len(envs) == 0 {
+						envs = []*jxcore.EnvironmentConfig{env}
+					}
+					if nativeAutoMerge && !autoMergeEnabled {
+						approved := true
+						if releaseInfo.Approval != nil {
+							approved, err = g.waitForApproval(pr, releaseInfo.Approval, promoteKey, &waitingForApproval)
+							if err != nil {
+								g.log.Warnf("failed to check the approvals of Pull Request %s: %s", pr.Link, err.Error())
+							}
+						}
+						if approved {
+							autoMergeEnabled, err = g.EnableNativeAutoMerge(envs, pr)
+							if err != nil {
+								g.log.Warnf("falling back to merging Pull Request %s when its checks pass: %s", pr.Link, err.Error())
+							}
+							nativeAutoMerge = autoMergeEnabled
+						}
+					}
+
+					prLastCommitSha := g.pullRequestLastCommitSha(pr)
 
-					status, err := o.PullRequestLastCommitStatus(pr)
+					status, err := g.PullRequestLastCommitStatus(pr, g.requiredStatusChecks(env))
 					switch {
 					case err != nil || status == nil:
-						log.Logger().Warnf("Failed to query the Pull Request last commit status for %s ref %s %s", pr.Link, prLastCommitSha, err)
+						g.log.Warnf("Failed to query the Pull Request last commit status for %s ref %s %s", pr.Link, prLastCommitSha, err)
 					case StateIsPending(status):
-						log.Logger().Info("The build for the Pull Request last commit is currently in progress.")
+						g.log.Infof("The build for the Pull Request last commit is currently in progress: %s", status.Desc)
 					default:
 						switch {
 						case status.State == scm.StateSuccess:
-							if !(o.NoMergePullRequest) {
+							if !g.NoMergePullRequest && !nativeAutoMerge {
+								approved, err := g.waitForApproval(pr, releaseInfo.Approval, promoteKey, &waitingForApproval)
+								if err != nil {
+									g.log.Warnf("failed to check the approvals of Pull Request %s: %s", pr.Link, err.Error())
+								}
+								if !approved {
+									break
+								}
 								tideMerge := false
 								// Now check if tide is running or not
 								commitStatues, _, err := scmClient.Repositories.ListStatus(ctx, fullName, prLastCommitSha, &scm.ListOptions{})
 								if err != nil {
-									log.Logger().Warnf("unable to get commit statuses for %s", pr.Link)
+									g.log.Warnf("unable to get commit statuses for %s", pr.Link)
 								} else {
 									for _, s := range commitStatues {
 										if s.Label == "tide" {
@@ -973,39 +1281,46 @@ func (o *Options) waitForGitOpsPullRequest(env *jxcore.EnvironmentConfig, releas
 									}
 								}
 								if !tideMerge {
-									prMergeOptions := &scm.PullRequestMergeOptions{
-										CommitTitle: "jx promote automatically merged promotion PR",
-									}
-									_, err = scmClient.PullRequests.Merge(ctx, fullName, prNumber, prMergeOptions)
-									// TODO: err = gitProvider.MergePullRequest(pr, "jx promote automatically merged promotion PR")
+									mergeErr, err := g.MergeIfMergeable(ctx, envs, pr)
 									if err != nil {
-										if !logMergeFailure {
-											logMergeFailure = true
-											log.Logger().Warnf("Failed to merge the Pull Request %s due to %s maybe I don't have karma?", pr.Link, err)
+										return err
+									}
+									if mergeErr != nil {
+										if lastMergeError == nil || lastMergeError.Error() != mergeErr.Error() {
+											g.log.Warnf("failed to merge the Pull Request %s: %s", pr.Link, mergeErr.Error())
 										}
+										lastMergeError = mergeErr
 									}
 								}
 							}
 						case StateIsErrorOrFailure(status):
-							return fmt.Errorf("pull request %s last commit has status %s for ref %s", pr.Link, status.State.String(), prLastCommitSha)
+							return fmt.Errorf("pull request %s last commit has status %s for ref %s: %s", pr.Link, status.State.String(), prLastCommitSha, status.Desc)
 						default:
-							log.Logger().Infof("got git provider status %s from PR %s", status.State.String(), pr.Link)
+							g.log.Infof("got git provider status %s from PR %s", status.State.String(), pr.Link)
 						}
 					}
 				}
-				if !pr.Mergeable {
-					log.Logger().Info("Rebasing PullRequest due to conflict")
-
-					err = o.PromoteViaPullRequest([]*jxcore.EnvironmentConfig{env}, releaseInfo, false)
+				delay, rebase, err := rebasePolicy.Next(pr, *g.PullRequestPollDuration)
+				if err != nil {
+					return err
+				}
+				if rebase {
+					err = g.RebasePullRequest(env, releaseInfo, pr, rebasePolicy)
 					if err != nil {
 						return err
 					}
+				} else if delay > 0 {
+					g.log.Infof("waiting %s for the git provider to compute whether Pull Request %s can be merged", delay.String(), pr.Link)
+					time.Sleep(delay)
 				}
 			}
 			if time.Now().After(end) {
-				return fmt.Errorf("timed out waiting for pull request %s to merge. Waited %s", pr.Link, duration.String())
+				if lastMergeError != nil {
+					return fmt.Errorf("%w waiting for pull request %s to merge. Waited %s. The last attempt to merge failed: %s", ErrPromotionTimedOut, pr.Link, duration.String(), lastMergeError.Error())
+				}
+				return fmt.Errorf("%w waiting for pull request %s to merge. Waited %s", ErrPromotionTimedOut, pr.Link, duration.String())
 			}
-			time.Sleep(*o.PullRequestPollDuration)
+			g.waitForNextPoll(events, end)
 		}
 	}
 	return nil
@@ -1029,63 +1344,10 @@ func StateIsPending(status *scm.Status) bool {
 	}
 }
 
-func (o *Options) PullRequestLastCommitStatus(pr *scm.PullRequest) (*scm.Status, error) {
-	scmClient := o.ScmClient
-	if scmClient == nil {
-		return nil, fmt.Errorf("no ScmClient")
-	}
-
-	ctx := context.Background()
-
-	fullName := pr.Repository().FullName
-
-	prLastCommitSha := o.pullRequestLastCommitSha(pr)
-
-	// lets try merge if the status is good
-	statuses, _, err := scmClient.Repositories.ListStatus(ctx, fullName, prLastCommitSha, &scm.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to query repository %s for PR last commit status of %s: %w", fullName, prLastCommitSha, err)
-	}
-	if len(statuses) == 0 {
-		return nil, fmt.Errorf("no commit statuses returned for repository %s for PR last commit status of %s", fullName, prLastCommitSha)
-	}
-	// TODO how to find the last status - assume the first?
-	return statuses[0], nil
-}
-
 func (o *Options) pullRequestLastCommitSha(pr *scm.PullRequest) string {
 	return pr.Head.Sha
 }
 
-func (o *Options) findLatestVersion(app string) (string, error) {
-	charts, err := o.Helm().SearchCharts(app, true)
-	if err != nil {
-		return "", err
-	}
-
-	var maxSemVer *semver.Version
-	maxString := ""
-	for _, chart := range charts {
-		sv, err := semver.Parse(chart.ChartVersion)
-		if err != nil {
-			log.Logger().Warnf("Invalid semantic version: %s %s", chart.ChartVersion, err)
-			if maxString == "" || chart.ChartVersion > maxString {
-				maxString = chart.ChartVersion
-			}
-		} else if maxSemVer == nil || maxSemVer.Compare(sv) > 0 {
-			maxSemVer = &sv
-		}
-	}
-
-	if maxSemVer != nil {
-		return maxSemVer.String(), nil
-	}
-	if maxString == "" {
-		return "", fmt.Errorf("could not find a version of app %s in the helm repositories", app)
-	}
-	return maxString, nil
-}
-
 func (o *Options) getAllVersions(app string) ([]string, error) {
 	charts, err := o.Helm().SearchCharts(app, true)
 	if err != nil {
@@ -1115,39 +1377,41 @@ func (o *Options) Helm() helm.Helmer {
 	return o.Helmer
 }
 
-func (o *Options) CreatePromoteKey(env *jxcore.EnvironmentConfig) *activities.PromoteStepActivityKey {
-	pipeline := o.Pipeline
+// resolveBuildInfo defaults the build number and discovers the git repository of the application once before
+// promoting so that the groups of environments promoted concurrently only read them
+func (o *Options) resolveBuildInfo() {
 	if o.Build == "" {
 		o.Build = builds.GetBuildNumber()
 	}
+	if o.GitInfo == nil && !o.IgnoreLocalFiles {
+		var err error
+		o.GitInfo, err = gitdiscovery.FindGitInfoFromDir(o.Dir)
+		if err != nil {
+			log.Logger().Warnf("Could not discover the Git repository info %s", err)
+		}
+	}
+}
+
+func (o *Options) CreatePromoteKey(env *jxcore.EnvironmentConfig) *activities.PromoteStepActivityKey {
+	pipeline := o.Pipeline
 	build := o.Build
+	if build == "" {
+		build = builds.GetBuildNumber()
+	}
 	buildURL := os.Getenv("BUILD_URL")
 	buildLogsURL := os.Getenv("BUILD_LOG_URL")
 	releaseNotesURL := ""
 	gitInfo := o.GitInfo
 	if !o.IgnoreLocalFiles {
-		var err error
-		if gitInfo == nil {
-			o.GitInfo, err = gitdiscovery.FindGitInfoFromDir(o.Dir)
-			if err != nil {
-				log.Logger().Warnf("Could not discover the Git repository info %s", err)
-			}
-		}
-
 		releaseName := o.ReleaseName
-		if o.releaseResource == nil && releaseName != "" {
-			jxClient := o.JXClient
-			if err == nil && jxClient != nil {
-				ens := EnvironmentNamespace(env)
-				release, err := jxClient.JenkinsV1().Releases(ens).Get(context.TODO(), releaseName, metav1.GetOptions{})
-				if err == nil && release != nil {
-					o.releaseResource = release
-				}
+		jxClient := o.JXClient
+		if releaseName != "" && jxClient != nil {
+			ens := EnvironmentNamespace(env)
+			release, err := jxClient.JenkinsV1().Releases(ens).Get(context.TODO(), releaseName, metav1.GetOptions{})
+			if err == nil && release != nil {
+				releaseNotesURL = release.Spec.ReleaseNotesURL
 			}
 		}
-		if o.releaseResource != nil {
-			releaseNotesURL = o.releaseResource.Spec.ReleaseNotesURL
-		}
 	}
 	if pipeline == "" {
 		pipeline, build = o.GetPipelineName(gitInfo, pipeline, build, o.Application)
@@ -1287,35 +1551,35 @@ func (o *Options) GetLatestPipelineBuild(pipeline string) (string, string, error
 }
 
 // CommentOnIssues comments on any issues for a release that the fix is available in the given environment
-func (o *Options) CommentOnIssues(environment *jxcore.EnvironmentConfig, promoteKey *activities.PromoteStepActivityKey) error {
+func (g *GroupContext) CommentOnIssues(environment *jxcore.EnvironmentConfig, promoteKey *activities.PromoteStepActivityKey) error {
 	ens := EnvironmentNamespace(environment)
 	envName := environment.Key
-	app := o.Application
-	version := o.Version
+	app := g.Application
+	version := g.Version
 	if ens == "" {
-		log.Logger().Warnf("Environment %s has no namespace", envName)
+		g.log.Warnf("Environment %s has no namespace", envName)
 		return nil
 	}
 	if app == "" {
-		log.Logger().Warnf("No application name so cannot comment on issues that they are now in %s", envName)
+		g.log.Warnf("No application name so cannot comment on issues that they are now in %s", envName)
 		return nil
 	}
 	if version == "" {
-		log.Logger().Warnf("No version name so cannot comment on issues that they are now in %s", envName)
+		g.log.Warnf("No version name so cannot comment on issues that they are now in %s", envName)
 		return nil
 	}
-	gitInfo := o.GitInfo
+	gitInfo := g.GitInfo
 	if gitInfo == nil {
-		log.Logger().Warnf("No GitInfo discovered so cannot comment on issues that they are now in %s", envName)
+		g.log.Warnf("No GitInfo discovered so cannot comment on issues that they are now in %s", envName)
 		return nil
 	}
 
 	var err error
 	releaseName := naming.ToValidNameWithDots(app + "-" + version)
-	jxClient := o.JXClient
-	kubeClient := o.KubeClient
+	jxClient := g.JXClient
+	kubeClient := g.KubeClient
 
-	appNames := []string{app, o.ReleaseName, ens + "-" + app}
+	appNames := []string{app, g.ReleaseName, ens + "-" + app}
 	svcURL := ""
 	for _, n := range appNames {
 		svcURL, err = services.FindServiceURL(kubeClient, ens, naming.ToValidName(n))
@@ -1327,7 +1591,7 @@ func (o *Options) CommentOnIssues(environment *jxcore.EnvironmentConfig, promote
 		}
 	}
 	if svcURL == "" {
-		log.Logger().Warnf("Could not find the service URL in namespace %s for names %s", ens, strings.Join(appNames, ", "))
+		g.log.Warnf("Could not find the service URL in namespace %s for names %s", ens, strings.Join(appNames, ", "))
 	}
 	available := ""
 	if svcURL != "" {
@@ -1336,8 +1600,8 @@ func (o *Options) CommentOnIssues(environment *jxcore.EnvironmentConfig, promote
 
 	if available == "" {
 		ing, err := kubeClient.ExtensionsV1beta1().Ingresses(ens).Get(context.TODO(), app, metav1.GetOptions{})
-		if err != nil || ing == nil && o.ReleaseName != "" && o.ReleaseName != app {
-			ing, err = kubeClient.ExtensionsV1beta1().Ingresses(ens).Get(context.TODO(), o.ReleaseName, metav1.GetOptions{})
+		if err != nil || ing == nil && g.ReleaseName != "" && g.ReleaseName != app {
+			ing, err = kubeClient.ExtensionsV1beta1().Ingresses(ens).Get(context.TODO(), g.ReleaseName, metav1.GetOptions{})
 			if err != nil {
 				return err
 			}
@@ -1356,12 +1620,11 @@ func (o *Options) CommentOnIssues(environment *jxcore.EnvironmentConfig, promote
 	// lets try update the PipelineActivity
 	if svcURL != "" && promoteKey.ApplicationURL == "" {
 		promoteKey.ApplicationURL = svcURL
-		log.Logger().Debugf("Application is available at: %s", termcolor.ColorInfo(svcURL))
+		g.log.Debugf("Application is available at: %s", termcolor.ColorInfo(svcURL))
 	}
 
 	release, err := jxClient.JenkinsV1().Releases(ens).Get(context.TODO(), releaseName, metav1.GetOptions{})
 	if err == nil && release != nil {
-		o.releaseResource = release
 		issues := release.Spec.Issues
 
 		versionMessage := version
@@ -1371,23 +1634,23 @@ func (o *Options) CommentOnIssues(environment *jxcore.EnvironmentConfig, promote
 		for k := range issues {
 			issue := issues[k]
 			if issue.IsClosed() {
-				log.Logger().Infof("Commenting that issue %s is now in %s", termcolor.ColorInfo(issue.URL), termcolor.ColorInfo(envName))
+				g.log.Infof("Commenting that issue %s is now in %s", termcolor.ColorInfo(issue.URL), termcolor.ColorInfo(envName))
 
 				comment := fmt.Sprintf(":white_check_mark: the fix for this issue is now deployed to **%s** in version %s %s", envName, versionMessage, available)
 				id := issue.ID
 				if id != "" {
 					number, err := strconv.Atoi(id)
 					if err != nil {
-						log.Logger().Warnf("Could not parse issue id %s for URL %s", id, issue.URL)
+						g.log.Warnf("Could not parse issue id %s for URL %s", id, issue.URL)
 					} else if number > 0 {
 						ctx := context.Background()
 						fullName := scm.Join(gitInfo.Organisation, gitInfo.Name)
-						_, _, err = o.ScmClient.Issues.CreateComment(ctx, fullName, number,
+						_, _, err = g.PullRequest.ScmClient.Issues.CreateComment(ctx, fullName, number,
 							&scm.CommentInput{
 								Body: comment,
 							})
 						if err != nil {
-							log.Logger().Warnf("Failed to add comment to issue %s: %s", issue.URL, err)
+							g.log.Warnf("Failed to add comment to issue %s: %s", issue.URL, err)
 						}
 					}
 				}
@@ -0,0 +1,143 @@
+package promote
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/notify"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/activities"
+)
+
+// PromotionMode returns how changes are promoted to the environment defaulting to via a Pull Request
+func (o *Options) PromotionMode(env *jxcore.EnvironmentConfig) (v1alpha1.PromotionMode, error) {
+	envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+	if envSpec == nil {
+		return v1alpha1.PromotionModePullRequest, nil
+	}
+	switch envSpec.PromotionMode {
+	case "", v1alpha1.PromotionModePullRequest:
+		return v1alpha1.PromotionModePullRequest, nil
+	case v1alpha1.PromotionModePush:
+		return v1alpha1.PromotionModePush, nil
+	default:
+		return "", fmt.Errorf("invalid promotionMode %s of environment %s: expected %s or %s", envSpec.PromotionMode, env.Key, v1alpha1.PromotionModePullRequest, v1alpha1.PromotionModePush)
+	}
+}
+
+// GroupPromotionMode returns how changes are promoted to the group of environments. The environments of a group share a
+// single commit so they must use the same promotion mode. Pushing would bypass the draft Pull Request of a manual
+// environment or a change freeze and the required approvals so those promotions fall back to a Pull Request
+func (g *GroupContext) GroupPromotionMode(envs []*jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo) (v1alpha1.PromotionMode, error) {
+	var mode v1alpha1.PromotionMode
+	for i, env := range envs {
+		envMode, err := g.PromotionMode(env)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 && envMode != mode {
+			return "", fmt.Errorf("environments %s cannot be promoted to together as they use different promotion modes: use the same promotionMode or --no-pr-group", (&GroupResult{Environments: envs}).Keys())
+		}
+		mode = envMode
+	}
+	if mode != v1alpha1.PromotionModePush {
+		return mode, nil
+	}
+	for _, env := range envs {
+		if g.draftPullRequest(env, releaseInfo.Freeze) {
+			g.log.Warnf("promoting to environment %s via a Pull Request rather than pushing as the promotion must not be merged automatically", env.Key)
+			return v1alpha1.PromotionModePullRequest, nil
+		}
+	}
+	if releaseInfo.Approval != nil {
+		g.log.Warnf("promoting to environments %s via a Pull Request rather than pushing as the promotion requires %s", (&GroupResult{Environments: envs}).Keys(), releaseInfo.Approval.String())
+		return v1alpha1.PromotionModePullRequest, nil
+	}
+	return mode, nil
+}
+
+// PromoteViaPush promotes the apps to the environments by committing the changes and pushing them directly to the
+// base branch of the environment repository. The SHA of the pushed commit is stored in the release info
+func (g *GroupContext) PromoteViaPush(envs []*jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo) error {
+	apps := g.PromoteApps()
+
+	changelog, err := appsChangelog(apps)
+	if err != nil {
+		return err
+	}
+	g.PullRequest.CommitChangelog = changelog
+	g.PullRequest.CommitTitle = "chore: promote " + appsTitle(apps)
+	g.PullRequest.CommitMessage = ""
+	g.PullRequest.FullCommitMessage = ""
+	if g.PromoteConfig != nil && g.PromoteConfig.Spec.PullRequest != nil {
+		err = g.RenderPullRequestTemplates(envs, g.PromoteConfig.Spec.PullRequest, changelog, "")
+		if err != nil {
+			return err
+		}
+	}
+	g.PullRequest.Function = g.promoteFunction(envs, apps)
+
+	gitURL, err := g.EnvironmentGitURL(envs[0])
+	if err != nil {
+		return err
+	}
+	sha, err := g.PullRequest.Push(gitURL)
+	if err != nil {
+		return err
+	}
+	releaseInfo.PushCommitSHA = sha
+	return nil
+}
+
+// promoteViaPush pushes the promotion to the environments recording the pushed commit in the PipelineActivity
+func (g *GroupContext) promoteViaPush(envs []*jxcore.EnvironmentConfig, env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, promoteKey *activities.PromoteStepActivityKey) error {
+	err := g.PromoteViaPush(envs, releaseInfo)
+	if err != nil {
+		g.notify(notify.EventPromotionFailed, env, releaseInfo, promoteKey, err)
+		return err
+	}
+
+	sha := releaseInfo.PushCommitSHA
+	pushed := func(a *v1.PipelineActivity, s *v1.PipelineActivityStep, ps *v1.PromoteActivityStep, p *v1.PromotePullRequestStep) error {
+		err := activities.StartPromotionPullRequest(a, s, ps, p)
+		if err != nil {
+			return err
+		}
+		err = activities.CompletePromotionPullRequest(a, s, ps, p)
+		if err != nil {
+			return err
+		}
+		p.MergeCommitSHA = sha
+		if g.Version != "" && a.Spec.Version == "" {
+			a.Spec.Version = g.Version
+		}
+		activities.UpdateStatus(a, false, nil)
+		return nil
+	}
+	err = g.onPromotePullRequest(promoteKey, pushed)
+	if err != nil {
+		g.log.Warnf("Failed to update PipelineActivity: %s", err)
+	}
+	if g.NoWaitAfterMerge {
+		g.log.Infof("Changes are pushed, No wait on promotion to complete")
+		return nil
+	}
+
+	err = g.onPromoteUpdate(promoteKey, activities.StartPromotionUpdate)
+	if err != nil {
+		return err
+	}
+	err = g.CommentOnIssues(env, promoteKey)
+	releaseInfo.ApplicationURL = promoteKey.ApplicationURL
+	if err == nil {
+		err = g.onPromoteUpdate(promoteKey, activities.CompletePromotionUpdate)
+	}
+	if err != nil {
+		g.notify(notify.EventPromotionFailed, env, releaseInfo, promoteKey, err)
+		return err
+	}
+	g.notify(notify.EventPromotionSucceeded, env, releaseInfo, promoteKey, nil)
+	return nil
+}
@@ -0,0 +1,121 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotionMode(t *testing.T) {
+	o := &promote.Options{
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:           "staging",
+						PromotionMode: v1alpha1.PromotionModePush,
+					},
+					{
+						Key:           "production",
+						PromotionMode: v1alpha1.PromotionModePullRequest,
+					},
+					{
+						Key:           "dr",
+						PromotionMode: "merge",
+					},
+				},
+			},
+		},
+	}
+
+	for key, expected := range map[string]v1alpha1.PromotionMode{
+		"staging":    v1alpha1.PromotionModePush,
+		"production": v1alpha1.PromotionModePullRequest,
+		"preview":    v1alpha1.PromotionModePullRequest,
+	} {
+		mode, err := o.PromotionMode(&jxcore.EnvironmentConfig{Key: key})
+		require.NoError(t, err, "failed to get the promotion mode of %s", key)
+		assert.Equal(t, expected, mode, "promotion mode of %s", key)
+	}
+
+	_, err := o.PromotionMode(&jxcore.EnvironmentConfig{Key: "dr"})
+	require.Error(t, err, "should fail for an invalid promotion mode")
+	assert.Contains(t, err.Error(), "invalid promotionMode merge", "error")
+}
+
+func TestGroupPromotionMode(t *testing.T) {
+	o := &promote.Options{
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:           "staging",
+						PromotionMode: v1alpha1.PromotionModePush,
+					},
+					{
+						Key:           "qa",
+						PromotionMode: v1alpha1.PromotionModePush,
+					},
+					{
+						Key:           "production",
+						PromotionMode: v1alpha1.PromotionModePush,
+					},
+				},
+			},
+		},
+	}
+	staging := &jxcore.EnvironmentConfig{Key: "staging", PromotionStrategy: v1.PromotionStrategyTypeAutomatic}
+	qa := &jxcore.EnvironmentConfig{Key: "qa", PromotionStrategy: v1.PromotionStrategyTypeAutomatic}
+	production := &jxcore.EnvironmentConfig{Key: "production", PromotionStrategy: v1.PromotionStrategyTypeManual}
+	preview := &jxcore.EnvironmentConfig{Key: "preview", PromotionStrategy: v1.PromotionStrategyTypeAutomatic}
+
+	testCases := []struct {
+		name        string
+		envs        []*jxcore.EnvironmentConfig
+		releaseInfo *promote.ReleaseInfo
+		expected    v1alpha1.PromotionMode
+	}{
+		{
+			name:        "push",
+			envs:        []*jxcore.EnvironmentConfig{staging, qa},
+			releaseInfo: &promote.ReleaseInfo{},
+			expected:    v1alpha1.PromotionModePush,
+		},
+		{
+			name:        "manual",
+			envs:        []*jxcore.EnvironmentConfig{production},
+			releaseInfo: &promote.ReleaseInfo{},
+			expected:    v1alpha1.PromotionModePullRequest,
+		},
+		{
+			name:        "freeze",
+			envs:        []*jxcore.EnvironmentConfig{staging},
+			releaseInfo: &promote.ReleaseInfo{Freeze: &promote.ActiveFreeze{Environment: "staging"}},
+			expected:    v1alpha1.PromotionModePullRequest,
+		},
+		{
+			name:        "approval",
+			envs:        []*jxcore.EnvironmentConfig{staging},
+			releaseInfo: &promote.ReleaseInfo{Approval: &promote.ApprovalPolicy{Approvals: 1}},
+			expected:    v1alpha1.PromotionModePullRequest,
+		},
+	}
+	for _, tc := range testCases {
+		mode, err := o.NewGroupContext(tc.envs, "1.2.3").GroupPromotionMode(tc.envs, tc.releaseInfo)
+		require.NoError(t, err, "failed to get the promotion mode for %s", tc.name)
+		assert.Equal(t, tc.expected, mode, "promotion mode for %s", tc.name)
+	}
+
+	envs := []*jxcore.EnvironmentConfig{staging, preview}
+	_, err := o.NewGroupContext(envs, "1.2.3").GroupPromotionMode(envs, &promote.ReleaseInfo{})
+	require.Error(t, err, "should fail for a group with different promotion modes")
+	assert.Contains(t, err.Error(), "use different promotion modes", "error")
+}
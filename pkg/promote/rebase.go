@@ -0,0 +1,107 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+)
+
+const (
+	// DefaultMaxRebases the default maximum number of times the branch of a conflicting Pull Request is regenerated
+	DefaultMaxRebases = 3
+
+	// DefaultMaxUnknownMergeablePolls the default number of polls to back off for while the git provider computes
+	// whether a Pull Request can be merged before assuming it conflicts
+	DefaultMaxUnknownMergeablePolls = 5
+
+	// maxMergeableBackOff the maximum time to back off for while the mergeability of a Pull Request is unknown
+	maxMergeableBackOff = time.Minute
+)
+
+// RebasePolicy decides when to regenerate the branch of a Pull Request which cannot be merged on the latest base
+// branch. Git providers such as GitHub compute whether a Pull Request can be merged asynchronously so while the state
+// is unknown the policy backs off rather than rebasing
+type RebasePolicy struct {
+	// MaxAttempts the maximum number of times to rebase the Pull Request. Defaults to DefaultMaxRebases
+	MaxAttempts int
+
+	// MaxUnknownPolls the number of polls to back off for while the mergeability is unknown before assuming the
+	// Pull Request conflicts
+	MaxUnknownPolls int
+
+	attempts     int
+	unknownPolls int
+}
+
+// Attempts returns the number of times the Pull Request has been rebased
+func (p *RebasePolicy) Attempts() int {
+	return p.attempts
+}
+
+// maxAttempts returns the maximum number of times to rebase the Pull Request
+func (p *RebasePolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultMaxRebases
+	}
+	return p.MaxAttempts
+}
+
+// Next returns how long to back off for while the mergeability of the Pull Request is unknown or whether its branch
+// should be rebased. An error is returned if the Pull Request still conflicts after the maximum number of rebases
+func (p *RebasePolicy) Next(pr *scm.PullRequest, pollDuration time.Duration) (time.Duration, bool, error) {
+	if pr.Mergeable || pr.Merged || pr.Closed || pr.MergeableState == scm.MergeableStateMergeable {
+		p.unknownPolls = 0
+		return 0, false, nil
+	}
+	if pr.MergeableState != scm.MergeableStateConflicting && p.unknownPolls < p.MaxUnknownPolls {
+		p.unknownPolls++
+		delay := pollDuration << (p.unknownPolls - 1)
+		if delay > maxMergeableBackOff || delay <= 0 {
+			delay = maxMergeableBackOff
+		}
+		return delay, false, nil
+	}
+	if p.attempts >= p.maxAttempts() {
+		return 0, false, fmt.Errorf("pull request %s cannot be merged after %d attempts to rebase it", pr.Link, p.attempts)
+	}
+	p.attempts++
+	p.unknownPolls = 0
+	return 0, true, nil
+}
+
+// RebasePullRequest regenerates the branch of the Pull Request promoting to the environment by re-applying the
+// promotion rule to the latest base branch, recording the attempt as a comment on the Pull Request
+func (g *GroupContext) RebasePullRequest(env *jxcore.EnvironmentConfig, releaseInfo *ReleaseInfo, pr *scm.PullRequest, policy *RebasePolicy) error {
+	g.log.Infof("rebasing Pull Request %s as it cannot be merged: attempt %d of %d", termcolor.ColorInfo(pr.Link), policy.Attempts(), policy.maxAttempts())
+
+	scmClient := g.PullRequest.ScmClient
+	if scmClient != nil {
+		comment := &scm.CommentInput{
+			Body: fmt.Sprintf("this Pull Request cannot be merged so its branch is being regenerated on the latest %s branch: attempt %d of %d", pr.Base.Ref, policy.Attempts(), policy.maxAttempts()),
+		}
+		_, _, err := scmClient.PullRequests.CreateComment(context.Background(), pr.Repository().FullName, pr.Number, comment)
+		if err != nil {
+			g.log.Warnf("failed to comment on Pull Request %s: %s", pr.Link, err.Error())
+		}
+	}
+
+	// lets regenerate the changes of all the environments of a grouped Pull Request
+	envs := releaseInfo.Environments
+	if len(envs) == 0 {
+		envs = []*jxcore.EnvironmentConfig{env}
+	}
+	draftPR := false
+	for _, e := range envs {
+		draftPR = draftPR || g.draftPullRequest(e, releaseInfo.Freeze)
+	}
+	releaseInfo.PullRequestInfo = pr
+	err := g.PromoteViaPullRequest(envs, releaseInfo, draftPR)
+	if err != nil {
+		return fmt.Errorf("failed to rebase Pull Request %s: %w", pr.Link, err)
+	}
+	return nil
+}
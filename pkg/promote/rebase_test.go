@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebasePolicy(t *testing.T) {
+	poll := 20 * time.Second
+	policy := &promote.RebasePolicy{
+		MaxAttempts:     2,
+		MaxUnknownPolls: 3,
+	}
+	unknown := &scm.PullRequest{Link: "https://github.com/myorg/environment-production/pull/1"}
+	conflicting := &scm.PullRequest{Link: unknown.Link, MergeableState: scm.MergeableStateConflicting}
+	mergeable := &scm.PullRequest{Link: unknown.Link, Mergeable: true, MergeableState: scm.MergeableStateMergeable}
+
+	for i, expected := range []time.Duration{20 * time.Second, 40 * time.Second, time.Minute} {
+		delay, rebase, err := policy.Next(unknown, poll)
+		require.NoError(t, err, "unknown poll %d", i+1)
+		assert.False(t, rebase, "should not rebase while the mergeability is unknown on poll %d", i+1)
+		assert.Equal(t, expected, delay, "back off on poll %d", i+1)
+	}
+	delay, rebase, err := policy.Next(unknown, poll)
+	require.NoError(t, err, "failed after backing off")
+	assert.True(t, rebase, "should assume the Pull Request conflicts after backing off")
+	assert.Zero(t, delay, "delay")
+	assert.Equal(t, 1, policy.Attempts(), "attempts")
+
+	_, rebase, err = policy.Next(mergeable, poll)
+	require.NoError(t, err, "failed for a mergeable Pull Request")
+	assert.False(t, rebase, "should not rebase a mergeable Pull Request")
+
+	_, rebase, err = policy.Next(conflicting, poll)
+	require.NoError(t, err, "failed for a conflicting Pull Request")
+	assert.True(t, rebase, "should rebase a conflicting Pull Request without backing off")
+	assert.Equal(t, 2, policy.Attempts(), "attempts")
+
+	_, _, err = policy.Next(conflicting, poll)
+	require.Error(t, err, "should fail after the maximum number of rebases")
+	assert.Contains(t, err.Error(), "after 2 attempts", "error")
+}
+
+func TestConflictingPullRequestIsRebased(t *testing.T) {
+	scmClient, fakeData := fake.NewDefault()
+	o := &promote.Options{}
+	o.ScmClient = scmClient
+
+	pr := &scm.PullRequest{
+		Number:         1,
+		Link:           "https://github.com/myorg/environment-production/pull/1",
+		MergeableState: scm.MergeableStateConflicting,
+		Base: scm.PullRequestBranch{
+			Repo: scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: "myorg/environment-production"},
+		},
+	}
+	fakeData.PullRequests[pr.Number] = pr
+	envs := []*jxcore.EnvironmentConfig{{Key: "production"}}
+	g := o.NewGroupContext(envs, "1.2.3")
+
+	retryErr, err := g.MergeIfMergeable(context.Background(), envs, pr)
+	require.NoError(t, err, "should not fail for a conflicting Pull Request")
+	require.NoError(t, retryErr, "should not try to merge a conflicting Pull Request")
+	assert.False(t, fakeData.PullRequests[pr.Number].Merged, "the conflicting Pull Request should not be merged")
+
+	policy := &promote.RebasePolicy{MaxUnknownPolls: promote.DefaultMaxUnknownMergeablePolls}
+	_, rebase, err := policy.Next(pr, 10*time.Second)
+	require.NoError(t, err, "failed to decide whether to rebase")
+	assert.True(t, rebase, "the conflicting Pull Request should be rebased")
+
+	pr.MergeableState = scm.MergeableStateMergeable
+	retryErr, err = g.MergeIfMergeable(context.Background(), envs, pr)
+	require.NoError(t, err, "failed to merge the rebased Pull Request")
+	require.NoError(t, retryErr, "failed to merge the rebased Pull Request")
+	assert.True(t, fakeData.PullRequests[pr.Number].Merged, "the rebased Pull Request should be merged")
+}
@@ -0,0 +1,198 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/tracing"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/activities"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MinSoakTime returns the minimum soak time configured for the given environment
+func (o *Options) MinSoakTime(env *jxcore.EnvironmentConfig) time.Duration {
+	envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+	if envSpec == nil || envSpec.MinSoakTime == nil {
+		return 0
+	}
+	return envSpec.MinSoakTime.Duration
+}
+
+// soak the minimum time an environment must have been deployed before the following environments are promoted to
+type soak struct {
+	env        *jxcore.EnvironmentConfig
+	deployedAt time.Time
+	duration   time.Duration
+}
+
+// WaitForSoak waits until the environments of the previously promoted group have been deployed for their
+// minSoakTime before we promote to the given environment.
+//
+// The deployedAt time is when the previous group was deployed by this command. If it is zero the deployment time
+// is looked up from the PipelineActivity so that a previous promotion can be resumed.
+func (o *Options) WaitForSoak(previous []*jxcore.EnvironmentConfig, deployedAt time.Time, env *jxcore.EnvironmentConfig) error {
+	return o.NewGroupContext([]*jxcore.EnvironmentConfig{env}, o.Version).waitForSoaks(o.previousSoaks(previous, deployedAt), env)
+}
+
+// previousSoaks returns the minSoakTime of each of the previously promoted environments
+func (o *Options) previousSoaks(previous []*jxcore.EnvironmentConfig, deployedAt time.Time) []soak {
+	var soaks []soak
+	for _, prev := range previous {
+		soaks = append(soaks, soak{env: prev, deployedAt: deployedAt, duration: o.MinSoakTime(prev)})
+	}
+	return soaks
+}
+
+// waitForSoaks waits until each environment has been deployed for its soak time before we promote to the given
+// environment. If it is not known when an environment was deployed the promotion fails so that it can be resumed
+// once the environment has been deployed
+func (g *GroupContext) waitForSoaks(soaks []soak, env *jxcore.EnvironmentConfig) error {
+	info := termcolor.ColorInfo
+	var soakEnv *jxcore.EnvironmentConfig
+	var soakTime time.Duration
+	var until time.Time
+	for _, s := range soaks {
+		prev := s.env
+		d := s.duration
+		if d <= 0 {
+			continue
+		}
+		t := s.deployedAt
+		if t.IsZero() {
+			t = g.deployedTime(prev)
+		}
+		if t.IsZero() {
+			if g.SkipSoak {
+				g.log.Infof("skipping the minSoakTime of %s for environment %s which has not been deployed as --%s is specified", info(d.String()), info(prev.Key), optionSkipSoak)
+				continue
+			}
+			g.pendingSoak(env, fmt.Sprintf("waiting for environment %s to be deployed and soak for %s", prev.Key, d.String()))
+			return fmt.Errorf("could not find when environment %s was deployed so cannot promote to %s until it has soaked for %s. Please rerun the promotion once %s has been deployed or use --%s", prev.Key, env.Key, d.String(), prev.Key, optionSkipSoak)
+		}
+		if t.Add(d).After(until) {
+			soakEnv = prev
+			soakTime = d
+			until = t.Add(d)
+		}
+	}
+	if soakEnv == nil {
+		return nil
+	}
+	if g.SkipSoak {
+		g.log.Infof("skipping the minSoakTime of %s for environment %s as --%s is specified", info(soakTime.String()), info(soakEnv.Key), optionSkipSoak)
+		return nil
+	}
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		return nil
+	}
+
+	promoteKey := g.CreatePromoteKey(env)
+	g.pendingSoak(env, fmt.Sprintf("waiting for environment %s to soak for %s until %s", soakEnv.Key, soakTime.String(), until.Format(time.RFC3339)))
+
+	if g.NoPoll || (g.TimeoutDuration != nil && remaining > *g.TimeoutDuration) {
+		return fmt.Errorf("environment %s has not soaked for %s yet so cannot promote to %s. Please rerun the promotion after %s or use --%s", soakEnv.Key, soakTime.String(), env.Key, until.Format(time.RFC3339), optionSkipSoak)
+	}
+
+	g.log.Infof("waiting %s for environment %s to soak before promoting to %s", info(remaining.Round(time.Second).String()), info(soakEnv.Key), info(env.Key))
+	_, span := tracing.Start(g.PullRequest.Context, "soak", attribute.String("promote.environment", soakEnv.Key))
+	time.Sleep(remaining)
+	span.End()
+
+	err := g.onPromote(promoteKey, func(ps *v1.PromoteActivityStep) {
+		ps.Status = v1.ActivityStatusTypeRunning
+		ps.Description = fmt.Sprintf("environment %s soaked for %s", soakEnv.Key, soakTime.String())
+	})
+	if err != nil {
+		g.log.Warnf("Failed to update PipelineActivity: %s", err)
+	}
+	return nil
+}
+
+// pendingSoak records in the PipelineActivity that the promotion to the environment is pending until the previous
+// environments have soaked
+func (o *Options) pendingSoak(env *jxcore.EnvironmentConfig, description string) {
+	err := o.onPromote(o.CreatePromoteKey(env), func(ps *v1.PromoteActivityStep) {
+		ps.Status = v1.ActivityStatusTypePending
+		ps.Description = description
+	})
+	if err != nil {
+		log.Logger().Warnf("Failed to update PipelineActivity: %s", err)
+	}
+}
+
+// deployedTime returns the time the promotion to the given environment completed in the PipelineActivity or
+// a zero time if it is not known
+func (o *Options) deployedTime(env *jxcore.EnvironmentConfig) time.Time {
+	jxClient := o.JXClient
+	if jxClient == nil {
+		return time.Time{}
+	}
+	promoteKey := o.CreatePromoteKey(env)
+	if !promoteKey.IsValid() {
+		return time.Time{}
+	}
+	a, err := jxClient.JenkinsV1().PipelineActivities(o.Namespace).Get(context.TODO(), promoteKey.Name, metav1.GetOptions{})
+	if err != nil || a == nil {
+		return time.Time{}
+	}
+	for i := range a.Spec.Steps {
+		ps := a.Spec.Steps[i].Promote
+		if ps == nil || ps.Environment != env.Key {
+			continue
+		}
+		if ps.Update != nil && ps.Update.Status == v1.ActivityStatusTypeSucceeded && ps.Update.CompletedTimestamp != nil {
+			return ps.Update.CompletedTimestamp.Time
+		}
+		if ps.PullRequest != nil && ps.PullRequest.MergeCommitSHA != "" && ps.PullRequest.CompletedTimestamp != nil {
+			return ps.PullRequest.CompletedTimestamp.Time
+		}
+	}
+	return time.Time{}
+}
+
+// onPromote updates the Promote step of the PipelineActivity for the given key
+func (o *Options) onPromote(promoteKey *activities.PromoteStepActivityKey, fn func(ps *v1.PromoteActivityStep)) error {
+	jxClient := o.JXClient
+	if jxClient == nil || !promoteKey.IsValid() {
+		return nil
+	}
+	defer o.lockActivity()()
+
+	a, _, ps, _, err := promoteKey.GetOrCreatePromote(jxClient, o.Namespace)
+	if err != nil {
+		return err
+	}
+	fn(ps)
+	_, err = jxClient.JenkinsV1().PipelineActivities(o.Namespace).Update(context.TODO(), a, metav1.UpdateOptions{})
+	return err
+}
+
+// onPromotePullRequest updates the Pull Request of the Promote step of the PipelineActivity for the given key
+func (o *Options) onPromotePullRequest(promoteKey *activities.PromoteStepActivityKey, fn activities.PromotePullRequestFn) error {
+	defer o.lockActivity()()
+	return promoteKey.OnPromotePullRequest(o.KubeClient, o.JXClient, o.Namespace, fn)
+}
+
+// onPromoteUpdate updates the Update of the Promote step of the PipelineActivity for the given key
+func (o *Options) onPromoteUpdate(promoteKey *activities.PromoteStepActivityKey, fn activities.PromoteUpdateFn) error {
+	defer o.lockActivity()()
+	return promoteKey.OnPromoteUpdate(o.KubeClient, o.JXClient, o.Namespace, fn)
+}
+
+// lockActivity serialises updates of the PipelineActivity when promoting to groups of environments concurrently.
+// It returns the function to unlock
+func (o *Options) lockActivity() func() {
+	if o.activityLock == nil {
+		return func() {}
+	}
+	o.activityLock.Lock()
+	return o.activityLock.Unlock
+}
@@ -0,0 +1,92 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	v1fake "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newSoakOptions(soak time.Duration) *promote.Options {
+	o := &promote.Options{
+		Namespace:        "jx",
+		Pipeline:         "myorg/myapp/master",
+		Build:            "1",
+		IgnoreLocalFiles: true,
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:         "staging",
+						MinSoakTime: &metav1.Duration{Duration: soak},
+					},
+				},
+			},
+		},
+	}
+	o.JXClient = v1fake.NewSimpleClientset()
+	return o
+}
+
+func TestWaitForSoak(t *testing.T) {
+	staging := &jxcore.EnvironmentConfig{Key: "staging"}
+	canary := &jxcore.EnvironmentConfig{Key: "canary"}
+	previous := []*jxcore.EnvironmentConfig{staging}
+
+	o := newSoakOptions(time.Hour)
+	err := o.WaitForSoak(previous, time.Now().Add(-2*time.Hour), canary)
+	assert.NoError(t, err, "should not wait as staging has soaked")
+
+	o.NoPoll = true
+	err = o.WaitForSoak(previous, time.Now(), canary)
+	require.Error(t, err, "should not promote before staging has soaked")
+	t.Logf("got expected error: %s", err.Error())
+
+	paList, err := o.JXClient.JenkinsV1().PipelineActivities("jx").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list PipelineActivity resources")
+	require.Len(t, paList.Items, 1, "should have a PipelineActivity")
+	steps := paList.Items[0].Spec.Steps
+	require.Len(t, steps, 1, "should have a promote step")
+	require.NotNil(t, steps[0].Promote, "should have a promote step")
+	assert.Equal(t, "canary", steps[0].Promote.Environment, "promote step environment")
+	assert.Equal(t, v1.ActivityStatusTypePending, steps[0].Promote.Status, "promote step status")
+	assert.Contains(t, steps[0].Promote.Description, "staging", "promote step description")
+
+	o.SkipSoak = true
+	err = o.WaitForSoak(previous, time.Now(), canary)
+	assert.NoError(t, err, "should not wait when skipping the soak time")
+}
+
+func TestWaitForSoakUnknownDeployTime(t *testing.T) {
+	staging := &jxcore.EnvironmentConfig{Key: "staging"}
+	canary := &jxcore.EnvironmentConfig{Key: "canary"}
+	previous := []*jxcore.EnvironmentConfig{staging}
+
+	o := newSoakOptions(time.Hour)
+	err := o.WaitForSoak(previous, time.Time{}, canary)
+	require.Error(t, err, "should not promote when it is not known when staging was deployed")
+	assert.Contains(t, err.Error(), "could not find when environment staging was deployed", "error")
+
+	paList, err := o.JXClient.JenkinsV1().PipelineActivities("jx").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list PipelineActivity resources")
+	require.Len(t, paList.Items, 1, "should have a PipelineActivity")
+	steps := paList.Items[0].Spec.Steps
+	require.Len(t, steps, 1, "should have a promote step")
+	require.NotNil(t, steps[0].Promote, "should have a promote step")
+	assert.Equal(t, v1.ActivityStatusTypePending, steps[0].Promote.Status, "promote step status")
+
+	o.SkipSoak = true
+	err = o.WaitForSoak(previous, time.Time{}, canary)
+	assert.NoError(t, err, "should not wait when skipping the soak time")
+}
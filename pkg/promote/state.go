@@ -0,0 +1,415 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/naming"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// stateConfigMapKey the key in the ConfigMap data of the promotion state
+	stateConfigMapKey = "state.yaml"
+
+	// LabelPromoteState the label on the ConfigMaps storing the promotion state
+	LabelPromoteState = "jenkins.io/promote-state"
+
+	// AnnotationPromoteStateExpires the annotation of the time after which a ConfigMap storing the promotion state is
+	// removed by later promotions
+	AnnotationPromoteStateExpires = "jenkins.io/promote-state-expires"
+
+	// DefaultStateTTL how long the state of a promotion which did not complete is kept in a ConfigMap
+	DefaultStateTTL = 7 * 24 * time.Hour
+)
+
+// PromoteState the persisted state of a promotion so that it can be resumed if the promote command is restarted
+type PromoteState struct {
+	// Application the name of the application being promoted
+	Application string `json:"application"`
+
+	// Version the version being promoted
+	Version string `json:"version,omitempty"`
+
+	// Groups the state of each group of environments
+	Groups []GroupState `json:"groups,omitempty"`
+}
+
+// GroupState the state of promoting to a group of environments which share a Pull Request
+type GroupState struct {
+	// Environments the keys of the environments in the group
+	Environments []string `json:"environments"`
+
+	// Version the version promoted to the group
+	Version string `json:"version,omitempty"`
+
+	// GitURL the git URL of the repository of the Pull Request
+	GitURL string `json:"gitURL,omitempty"`
+
+	// Repository the full name of the repository of the Pull Request
+	Repository string `json:"repository,omitempty"`
+
+	// PullRequestNumber the number of the Pull Request
+	PullRequestNumber int `json:"pullRequestNumber,omitempty"`
+
+	// PullRequestURL the URL of the Pull Request
+	PullRequestURL string `json:"pullRequestURL,omitempty"`
+
+	// MergeSHA the merge commit SHA of the Pull Request once it has merged
+	MergeSHA string `json:"mergeSHA,omitempty"`
+
+	// Completed true if the promotion to the group has completed
+	Completed bool `json:"completed,omitempty"`
+}
+
+// FindGroup returns the state of the group of environments or nil if there is none
+func (s *PromoteState) FindGroup(envs []*jxcore.EnvironmentConfig) *GroupState {
+	key := environmentKeys(envs)
+	for i := range s.Groups {
+		if strings.Join(s.Groups[i].Environments, ",") == key {
+			return &s.Groups[i]
+		}
+	}
+	return nil
+}
+
+// environmentKeys returns the keys of the environments
+func environmentKeys(envs []*jxcore.EnvironmentConfig) string {
+	var keys []string
+	for _, env := range envs {
+		keys = append(keys, env.Key)
+	}
+	return strings.Join(keys, ",")
+}
+
+// stateStore loads and saves the promotion state in a file or a ConfigMap
+type stateStore struct {
+	lock  sync.Mutex
+	state *PromoteState
+	save  func(state *PromoteState) error
+	clear func() error
+}
+
+// LoadState loads the persisted state of the promotion from the --state-file or if --resume is specified a ConfigMap in
+// the current namespace. The state is not persisted if neither is specified and the previous state is only used if
+// --resume is specified
+func (o *Options) LoadState() error {
+	o.state = nil
+	if o.StateFile == "" && !o.Resume {
+		return nil
+	}
+	store := &stateStore{}
+	var data []byte
+	if o.StateFile != "" {
+		exists, err := files.FileExists(o.StateFile)
+		if err != nil {
+			return fmt.Errorf("failed to check if file exists %s: %w", o.StateFile, err)
+		}
+		if exists && o.Resume {
+			data, err = os.ReadFile(o.StateFile)
+			if err != nil {
+				return fmt.Errorf("failed to load file %s: %w", o.StateFile, err)
+			}
+		}
+		store.save = func(state *PromoteState) error {
+			data, err := yaml.Marshal(state)
+			if err != nil {
+				return fmt.Errorf("failed to marshal promotion state to YAML: %w", err)
+			}
+			return os.WriteFile(o.StateFile, data, files.DefaultFileWritePermissions)
+		}
+		store.clear = func() error {
+			return os.RemoveAll(o.StateFile)
+		}
+	} else {
+		kubeClient := o.KubeClient
+		if kubeClient == nil {
+			return nil
+		}
+		ctx := context.TODO()
+		ns := o.Namespace
+		name := o.stateConfigMapName()
+		configMaps := kubeClient.CoreV1().ConfigMaps(ns)
+		cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+				log.Logger().Warnf("not persisting the promotion state as ConfigMap %s in namespace %s cannot be read: %s", name, ns, err.Error())
+				return nil
+			}
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get ConfigMap %s in namespace %s: %w", name, ns, err)
+			}
+			cm = nil
+		}
+		if cm != nil {
+			data = []byte(cm.Data[stateConfigMapKey])
+		}
+		o.pruneExpiredStates(ctx, name)
+
+		store.save = func(state *PromoteState) error {
+			data, err := yaml.Marshal(state)
+			if err != nil {
+				return fmt.Errorf("failed to marshal promotion state to YAML: %w", err)
+			}
+			expires := time.Now().Add(DefaultStateTTL).UTC().Format(time.RFC3339)
+			cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				if !apierrors.IsNotFound(err) {
+					return fmt.Errorf("failed to get ConfigMap %s in namespace %s: %w", name, ns, err)
+				}
+				cm = &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: ns,
+						Labels: map[string]string{
+							LabelPromoteState: "true",
+						},
+						Annotations: map[string]string{
+							AnnotationPromoteStateExpires: expires,
+						},
+					},
+					Data: map[string]string{
+						stateConfigMapKey: string(data),
+					},
+				}
+				_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+				if err != nil {
+					return fmt.Errorf("failed to create ConfigMap %s in namespace %s: %w", name, ns, err)
+				}
+				return nil
+			}
+			if cm.Data == nil {
+				cm.Data = map[string]string{}
+			}
+			cm.Data[stateConfigMapKey] = string(data)
+			if cm.Annotations == nil {
+				cm.Annotations = map[string]string{}
+			}
+			cm.Annotations[AnnotationPromoteStateExpires] = expires
+			_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to update ConfigMap %s in namespace %s: %w", name, ns, err)
+			}
+			return nil
+		}
+		store.clear = func() error {
+			err := configMaps.Delete(ctx, name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete ConfigMap %s in namespace %s: %w", name, ns, err)
+			}
+			return nil
+		}
+	}
+
+	state := &PromoteState{}
+	if len(data) > 0 {
+		err := yaml.Unmarshal(data, state)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal the promotion state: %w", err)
+		}
+		if state.Application != o.Application || state.Version != o.Version {
+			log.Logger().Warnf("ignoring the previous promotion state of app %s version %s as promoting app %s version %s", state.Application, state.Version, o.Application, o.Version)
+			state = &PromoteState{}
+		} else {
+			log.Logger().Infof("resuming the promotion of app %s version %s", termcolor.ColorInfo(state.Application), termcolor.ColorInfo(state.Version))
+		}
+	}
+	state.Application = o.Application
+	state.Version = o.Version
+	store.state = state
+	o.state = store
+	return nil
+}
+
+// pruneExpiredStates removes the ConfigMaps storing the state of other promotions which have expired so that the
+// state of promotions which never complete is not kept forever
+func (o *Options) pruneExpiredStates(ctx context.Context, current string) {
+	configMaps := o.KubeClient.CoreV1().ConfigMaps(o.Namespace)
+	list, err := configMaps.List(ctx, metav1.ListOptions{LabelSelector: LabelPromoteState + "=true"})
+	if err != nil {
+		log.Logger().Debugf("failed to list the promotion state ConfigMaps in namespace %s: %s", o.Namespace, err.Error())
+		return
+	}
+	now := time.Now()
+	for i := range list.Items {
+		cm := &list.Items[i]
+		if cm.Name == current {
+			continue
+		}
+		expires, err := time.Parse(time.RFC3339, cm.Annotations[AnnotationPromoteStateExpires])
+		if err != nil || expires.After(now) {
+			continue
+		}
+		err = configMaps.Delete(ctx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Logger().Warnf("failed to delete the expired promotion state ConfigMap %s in namespace %s: %s", cm.Name, o.Namespace, err.Error())
+		}
+	}
+}
+
+// State returns the current state of the promotion or nil if it is not persisted
+func (o *Options) State() *PromoteState {
+	if o.state == nil {
+		return nil
+	}
+	return o.state.state
+}
+
+// stateConfigMapName returns the name of the ConfigMap storing the state of promoting the application version
+func (o *Options) stateConfigMapName() string {
+	name := "jx-promote-" + o.Application
+	if o.Version != "" {
+		name += "-" + o.Version
+	}
+	return naming.ToValidNameWithDotsTruncated(name, 63)
+}
+
+// updateGroupState updates the persisted state of the group of environments
+func (o *Options) updateGroupState(envs []*jxcore.EnvironmentConfig, fn func(gs *GroupState)) {
+	store := o.state
+	if store == nil {
+		return
+	}
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	gs := store.state.FindGroup(envs)
+	if gs == nil {
+		store.state.Groups = append(store.state.Groups, GroupState{
+			Environments: strings.Split(environmentKeys(envs), ","),
+		})
+		gs = &store.state.Groups[len(store.state.Groups)-1]
+	}
+	fn(gs)
+	err := store.save(store.state)
+	if err != nil {
+		log.Logger().Warnf("failed to save the promotion state: %s", err.Error())
+	}
+}
+
+// groupState returns a copy of the previous state of the group of environments or nil if there is none
+func (o *Options) groupState(envs []*jxcore.EnvironmentConfig) *GroupState {
+	store := o.state
+	if store == nil {
+		return nil
+	}
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	gs := store.state.FindGroup(envs)
+	if gs == nil {
+		return nil
+	}
+	answer := *gs
+	return &answer
+}
+
+// recordPullRequest records the Pull Request promoting to the group of environments
+func (o *Options) recordPullRequest(envs []*jxcore.EnvironmentConfig, version string, pr *scm.PullRequest) {
+	if pr == nil {
+		return
+	}
+	gitURL, err := o.EnvironmentGitURL(envs[0])
+	if err != nil {
+		log.Logger().Warnf("%s", err.Error())
+	}
+	o.updateGroupState(envs, func(gs *GroupState) {
+		gs.Version = version
+		gs.GitURL = gitURL
+		gs.Repository = pr.Repository().FullName
+		gs.PullRequestNumber = pr.Number
+		gs.PullRequestURL = pr.Link
+		gs.MergeSHA = pr.MergeSha
+	})
+}
+
+// ClearState removes the persisted state once the promotion has completed
+func (o *Options) ClearState() error {
+	store := o.state
+	if store == nil {
+		return nil
+	}
+	return store.clear()
+}
+
+// resumePullRequest returns the Pull Request created by a previous promotion to the group of environments if it can
+// still be used or nil if the environments should be promoted to again
+func (g *GroupContext) resumePullRequest(gs *GroupState) (*scm.PullRequest, error) {
+	if gs == nil || gs.PullRequestNumber == 0 || gs.Repository == "" {
+		return nil, nil
+	}
+	scmClient := g.PullRequest.ScmClient
+	if scmClient == nil {
+		var err error
+		scmClient, _, err = g.PullRequest.GetScmClient(gs.GitURL, g.GitKind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ScmClient: %w", err)
+		}
+		if scmClient == nil {
+			return nil, nil
+		}
+	}
+	pr, _, err := scmClient.PullRequests.Find(context.Background(), gs.Repository, gs.PullRequestNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PR %s %d: %w", gs.Repository, gs.PullRequestNumber, err)
+	}
+	if pr.Closed && !pr.Merged {
+		g.log.Warnf("Pull Request %s of the previous promotion is closed so promoting again", pr.Link)
+		return nil, nil
+	}
+	return pr, nil
+}
+
+// resumeReleaseInfo returns the release info for waiting on the Pull Request of a previous promotion
+func (g *GroupContext) resumeReleaseInfo(envs []*jxcore.EnvironmentConfig, pr *scm.PullRequest) (*ReleaseInfo, error) {
+	freeze, err := g.CheckFreeze(envs)
+	if err != nil {
+		return nil, err
+	}
+	releaseName := g.ReleaseName
+	if releaseName == "" {
+		releaseName = g.Application
+	}
+	return &ReleaseInfo{
+		ReleaseName:     releaseName,
+		FullAppName:     g.FullAppName(g.Application),
+		Version:         g.Version,
+		PullRequestInfo: pr,
+		Freeze:          freeze,
+		Approval:        g.ApprovalPolicy(envs...),
+		Environments:    envs,
+	}, nil
+}
+
+// clearStateIfCompleted removes the persisted state if the promotion to all the groups has completed
+func (o *Options) clearStateIfCompleted(results []*GroupResult) error {
+	store := o.state
+	if store == nil {
+		return nil
+	}
+	for _, r := range results {
+		if r.Error != nil {
+			return nil
+		}
+		if r.Skipped {
+			continue
+		}
+		gs := o.groupState(r.Environments)
+		if gs != nil && !gs.Completed {
+			return nil
+		}
+	}
+	return o.ClearState()
+}
@@ -0,0 +1,166 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestLoadStateFromFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.yaml")
+	err := files.CopyFile(filepath.Join("test_data", "state", "state.yaml"), stateFile)
+	require.NoError(t, err, "failed to copy state file")
+
+	o := &promote.Options{
+		Version:   "1.2.3",
+		StateFile: stateFile,
+	}
+	o.Application = "myapp"
+
+	err = o.LoadState()
+	require.NoError(t, err, "failed to load state")
+	assert.Empty(t, o.State().Groups, "should not use the previous state without --resume")
+
+	o.Resume = true
+	err = o.LoadState()
+	require.NoError(t, err, "failed to load state")
+	state := o.State()
+	require.Len(t, state.Groups, 2, "should have loaded the groups from %s", stateFile)
+
+	staging := state.FindGroup([]*jxcore.EnvironmentConfig{{Key: "staging"}})
+	require.NotNil(t, staging, "should have a staging group")
+	assert.True(t, staging.Completed, "staging should be completed")
+
+	production := state.FindGroup([]*jxcore.EnvironmentConfig{{Key: "production"}})
+	require.NotNil(t, production, "should have a production group")
+	assert.False(t, production.Completed, "production should not be completed")
+	assert.Equal(t, 7, production.PullRequestNumber, "production Pull Request")
+	assert.Equal(t, "myorg/environment-production", production.Repository, "production repository")
+
+	o.Version = "1.2.4"
+	err = o.LoadState()
+	require.NoError(t, err, "failed to load state")
+	assert.Empty(t, o.State().Groups, "should ignore the state of a different version")
+
+	err = o.ClearState()
+	require.NoError(t, err, "failed to clear state")
+	assert.NoFileExists(t, stateFile, "should have removed the state file")
+}
+
+func TestLoadStateFromConfigMap(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("test_data", "state", "state.yaml"))
+	require.NoError(t, err, "failed to load state")
+
+	o := &promote.Options{
+		Version: "1.2.3",
+		Resume:  true,
+	}
+	o.Application = "myapp"
+	o.Namespace = "jx"
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.LoadState()
+	require.NoError(t, err, "failed to load state")
+	assert.Empty(t, o.State().Groups, "should have no state without a ConfigMap")
+
+	cms := o.KubeClient.CoreV1().ConfigMaps("jx")
+	cm, err := cms.Create(context.TODO(), newStateConfigMap("jx-promote-myapp-1.2.3", string(data)), metav1.CreateOptions{})
+	require.NoError(t, err, "failed to create ConfigMap")
+
+	err = o.LoadState()
+	require.NoError(t, err, "failed to load state")
+	require.Len(t, o.State().Groups, 2, "should have loaded the groups from ConfigMap %s", cm.Name)
+
+	err = o.ClearState()
+	require.NoError(t, err, "failed to clear state")
+	list, err := cms.List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list ConfigMaps")
+	assert.Empty(t, list.Items, "should have removed the ConfigMap")
+}
+
+func TestLoadStateWithoutResume(t *testing.T) {
+	o := &promote.Options{Version: "1.2.3"}
+	o.Application = "myapp"
+	o.Namespace = "jx"
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.LoadState()
+	require.NoError(t, err, "failed to load state")
+	assert.Nil(t, o.State(), "should not persist the state without --resume or --state-file")
+}
+
+func TestLoadStateForbidden(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "jx-promote-myapp-1.2.3", nil)
+	})
+	o := &promote.Options{
+		Version: "1.2.3",
+		Resume:  true,
+	}
+	o.Application = "myapp"
+	o.Namespace = "jx"
+	o.KubeClient = kubeClient
+
+	err := o.LoadState()
+	require.NoError(t, err, "should not fail if the ConfigMap cannot be read")
+	assert.Nil(t, o.State(), "should not persist the state")
+}
+
+func TestLoadStatePrunesExpiredStates(t *testing.T) {
+	expired := newStateConfigMap("jx-promote-myapp-1.0.0", "")
+	expired.Annotations = map[string]string{promote.AnnotationPromoteStateExpires: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)}
+	current := newStateConfigMap("jx-promote-myapp-1.1.0", "")
+	current.Annotations = map[string]string{promote.AnnotationPromoteStateExpires: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)}
+
+	o := &promote.Options{
+		Version: "1.2.3",
+		Resume:  true,
+	}
+	o.Application = "myapp"
+	o.Namespace = "jx"
+	o.KubeClient = fake.NewSimpleClientset(expired, current)
+
+	err := o.LoadState()
+	require.NoError(t, err, "failed to load state")
+
+	cms := o.KubeClient.CoreV1().ConfigMaps("jx")
+	_, err = cms.Get(context.TODO(), expired.Name, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "should have removed the expired ConfigMap")
+	_, err = cms.Get(context.TODO(), current.Name, metav1.GetOptions{})
+	assert.NoError(t, err, "should keep the ConfigMap which has not expired")
+}
+
+func newStateConfigMap(name, data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "jx",
+			Labels: map[string]string{
+				promote.LabelPromoteState: "true",
+			},
+		},
+		Data: map[string]string{
+			"state.yaml": data,
+		},
+	}
+}
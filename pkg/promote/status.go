@@ -0,0 +1,193 @@
+package promote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+)
+
+const (
+	optionRequiredStatusCheck = "required-status-check"
+
+	// CombinedStatusLabel the label of the status combining the statuses and check runs of a commit
+	CombinedStatusLabel = "combined"
+)
+
+// checkRuns the check runs of a commit returned by the GitHub API
+type checkRuns struct {
+	CheckRuns []struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		HTMLURL    string `json:"html_url"`
+		DetailsURL string `json:"details_url"`
+	} `json:"check_runs"`
+}
+
+// requiredStatusChecks returns the commit status contexts and check run names which must succeed before the Pull
+// Request promoting to the environment is merged or nil if all of them must succeed
+func (o *Options) requiredStatusChecks(env *jxcore.EnvironmentConfig) []string {
+	if env != nil {
+		envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+		if envSpec != nil && len(envSpec.RequiredStatusChecks) > 0 {
+			return envSpec.RequiredStatusChecks
+		}
+	}
+	return o.RequiredChecks
+}
+
+// PullRequestLastCommitStatus returns the status combining the latest commit status of each context and the check
+// runs of the last commit of the Pull Request. If required contexts are specified only they are combined
+func (g *GroupContext) PullRequestLastCommitStatus(pr *scm.PullRequest, required []string) (*scm.Status, error) {
+	scmClient := g.PullRequest.ScmClient
+	if scmClient == nil {
+		return nil, fmt.Errorf("no ScmClient")
+	}
+
+	ctx := context.Background()
+
+	fullName := pr.Repository().FullName
+
+	prLastCommitSha := g.pullRequestLastCommitSha(pr)
+
+	statuses, _, err := scmClient.Repositories.ListStatus(ctx, fullName, prLastCommitSha, &scm.ListOptions{Size: 100})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repository %s for PR last commit status of %s: %w", fullName, prLastCommitSha, err)
+	}
+	checks, err := listCheckRuns(ctx, scmClient, fullName, prLastCommitSha)
+	if err != nil {
+		g.log.Warnf("failed to query the check runs of %s for PR last commit %s: %s", fullName, prLastCommitSha, err.Error())
+	}
+	statuses = append(statuses, checks...)
+	if len(statuses) == 0 && len(required) == 0 {
+		return nil, fmt.Errorf("no commit statuses returned for repository %s for PR last commit status of %s", fullName, prLastCommitSha)
+	}
+	return CombineStatuses(statuses, required), nil
+}
+
+// CombineStatuses combines the latest status of each context into a single status. The git providers return the
+// statuses of a commit with the latest first. If required contexts are specified the other contexts are ignored and
+// any missing required context is pending. The description of a failed status names the failing contexts and their
+// target URLs
+func CombineStatuses(statuses []*scm.Status, required []string) *scm.Status {
+	latest := map[string]*scm.Status{}
+	var contexts []string
+	for _, s := range statuses {
+		if s == nil || latest[s.Label] != nil {
+			continue
+		}
+		latest[s.Label] = s
+		contexts = append(contexts, s.Label)
+	}
+	if len(required) > 0 {
+		contexts = required
+	}
+
+	var failed, pending []string
+	answer := &scm.Status{
+		State: scm.StateSuccess,
+		Label: CombinedStatusLabel,
+	}
+	for _, c := range contexts {
+		s := latest[c]
+		switch {
+		case s == nil:
+			pending = append(pending, c+" (missing)")
+		case StateIsErrorOrFailure(s):
+			failed = append(failed, statusDescription(s))
+			if answer.Target == "" {
+				answer.Target = s.Target
+			}
+			if !StateIsErrorOrFailure(answer) {
+				answer.State = s.State
+			}
+		case s.State != scm.StateSuccess:
+			pending = append(pending, statusDescription(s))
+		}
+	}
+	switch {
+	case len(failed) > 0:
+		answer.Desc = "failing checks: " + strings.Join(failed, ", ")
+	case len(pending) > 0:
+		answer.State = scm.StatePending
+		answer.Desc = "pending checks: " + strings.Join(pending, ", ")
+	default:
+		answer.Desc = "all checks succeeded"
+	}
+	return answer
+}
+
+// statusDescription returns the context and state of the status along with its target URL if it has one
+func statusDescription(s *scm.Status) string {
+	text := s.Label + " " + s.State.String()
+	if s.Target != "" {
+		text += " " + s.Target
+	}
+	return text
+}
+
+// listCheckRuns returns the check runs of the commit as statuses for git providers which support them
+func listCheckRuns(ctx context.Context, scmClient *scm.Client, fullName, sha string) ([]*scm.Status, error) {
+	if scmClient.Driver != scm.DriverGithub {
+		return nil, nil
+	}
+	res, err := scmClient.Do(ctx, &scm.Request{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("repos/%s/commits/%s/check-runs?per_page=100", fullName, sha),
+		Header: http.Header{"Accept": []string{"application/vnd.github+json"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the check runs: %w", err)
+	}
+	if res.Status != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", res.Status, string(data))
+	}
+	runs := &checkRuns{}
+	err = json.Unmarshal(data, runs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the check runs: %w", err)
+	}
+
+	var answer []*scm.Status
+	for _, r := range runs.CheckRuns {
+		target := r.HTMLURL
+		if target == "" {
+			target = r.DetailsURL
+		}
+		answer = append(answer, &scm.Status{
+			State:  checkRunState(r.Status, r.Conclusion),
+			Label:  r.Name,
+			Target: target,
+		})
+	}
+	return answer, nil
+}
+
+// checkRunState converts the status and conclusion of a check run into the state of a commit status
+func checkRunState(status, conclusion string) scm.State {
+	if status != "completed" {
+		return scm.StatePending
+	}
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return scm.StateSuccess
+	case "cancelled":
+		return scm.StateCanceled
+	case "stale":
+		return scm.StatePending
+	default:
+		return scm.StateFailure
+	}
+}
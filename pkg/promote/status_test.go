@@ -0,0 +1,78 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombineStatuses(t *testing.T) {
+	statuses := []*scm.Status{
+		{Label: "pr-build", State: scm.StateSuccess},
+		{Label: "lint", State: scm.StateFailure, Target: "https://ci.example.com/lint/2"},
+		{Label: "pr-build", State: scm.StateFailure, Target: "https://ci.example.com/pr-build/1"},
+		{Label: "e2e", State: scm.StatePending},
+	}
+
+	status := promote.CombineStatuses(statuses, nil)
+	assert.Equal(t, scm.StateFailure, status.State, "combined state")
+	assert.Equal(t, "failing checks: lint failure https://ci.example.com/lint/2", status.Desc, "combined description")
+	assert.Equal(t, "https://ci.example.com/lint/2", status.Target, "combined target")
+
+	status = promote.CombineStatuses(statuses, []string{"pr-build", "e2e"})
+	assert.Equal(t, scm.StatePending, status.State, "should ignore the failing lint which is not required")
+
+	status = promote.CombineStatuses(statuses, []string{"pr-build"})
+	assert.Equal(t, scm.StateSuccess, status.State, "the latest pr-build status succeeded")
+
+	status = promote.CombineStatuses(statuses, []string{"pr-build", "security"})
+	assert.Equal(t, scm.StatePending, status.State, "should wait for the missing required context")
+	assert.Contains(t, status.Desc, "security (missing)", "combined description")
+}
+
+func TestPullRequestLastCommitStatusCheckRuns(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/myorg/environment-production/statuses/abc123", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"state": "success", "context": "pr-build", "target_url": "https://ci.example.com/pr-build/1"}]`))
+	})
+	mux.HandleFunc("/repos/myorg/environment-production/commits/abc123/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"total_count": 2, "check_runs": [
+  {"name": "policy", "status": "completed", "conclusion": "failure", "html_url": "https://github.com/myorg/environment-production/runs/7"},
+  {"name": "kubeconform", "status": "in_progress"}
+]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	scmClient, err := github.New(server.URL)
+	require.NoError(t, err, "failed to create the github client")
+
+	o := &promote.Options{}
+	o.ScmClient = scmClient
+	pr := &scm.PullRequest{
+		Number: 1,
+		Head:   scm.PullRequestBranch{Sha: "abc123"},
+		Base: scm.PullRequestBranch{
+			Repo: scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: "myorg/environment-production"},
+		},
+	}
+
+	g := o.NewGroupContext(nil, "")
+	status, err := g.PullRequestLastCommitStatus(pr, nil)
+	require.NoError(t, err, "failed to get the last commit status")
+	assert.Equal(t, scm.StateFailure, status.State, "combined state")
+	assert.Equal(t, "failing checks: policy failure https://github.com/myorg/environment-production/runs/7", status.Desc, "combined description")
+
+	status, err = g.PullRequestLastCommitStatus(pr, []string{"pr-build", "kubeconform"})
+	require.NoError(t, err, "failed to get the last commit status")
+	assert.Equal(t, scm.StatePending, status.State, "should wait for the kubeconform check run")
+}
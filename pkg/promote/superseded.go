@@ -0,0 +1,120 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/scmhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+)
+
+// CloseSupersededPullRequests closes any other open Pull Requests with the same environment and dependency labels as
+// the Pull Request which promote an older version, such as those held from merging, commenting with a link to the
+// Pull Request and deleting their branches. Pull Requests whose version cannot be found are left open
+func (g *GroupContext) CloseSupersededPullRequests(pr *scm.PullRequest, labels []string) error {
+	if pr == nil || len(labels) == 0 {
+		return nil
+	}
+	version, err := semver.NewVersion(g.Version)
+	if err != nil {
+		g.log.Warnf("not closing superseded Pull Requests as version %s is not a semantic version: %s", g.Version, err.Error())
+		return nil
+	}
+	scmClient := g.PullRequest.ScmClient
+	if scmClient == nil {
+		return fmt.Errorf("no ScmClient")
+	}
+	ctx := context.Background()
+	fullName := pr.Repository().FullName
+	prs, _, err := scmClient.PullRequests.List(ctx, fullName, &scm.PullRequestListOptions{
+		Size:   100,
+		Open:   true,
+		Labels: labels,
+	})
+	if scmhelpers.IsScmNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list open Pull Requests of %s: %w", fullName, err)
+	}
+
+Prs:
+	for _, old := range prs {
+		if old.Number == pr.Number || old.Closed || old.Merged {
+			continue
+		}
+		for _, label := range labels {
+			if !scmhelpers.ContainsLabel(old.Labels, label) {
+				continue Prs
+			}
+		}
+		oldVersion := pullRequestVersion(old, g.Application)
+		if oldVersion == nil {
+			g.log.Infof("not closing Pull Request %s as its version could not be found", termcolor.ColorInfo(old.Link))
+			continue
+		}
+		if !oldVersion.LessThan(version) {
+			continue
+		}
+		comment := &scm.CommentInput{
+			Body: fmt.Sprintf("superseded by %s", pr.Link),
+		}
+		_, _, err = scmClient.PullRequests.CreateComment(ctx, fullName, old.Number, comment)
+		if err != nil {
+			return fmt.Errorf("failed to comment on superseded Pull Request %s: %w", old.Link, err)
+		}
+		_, err = scmClient.PullRequests.Close(ctx, fullName, old.Number)
+		if err != nil {
+			return fmt.Errorf("failed to close superseded Pull Request %s: %w", old.Link, err)
+		}
+		g.log.Infof("closed superseded Pull Request %s", termcolor.ColorInfo(old.Link))
+
+		if old.Source == "" || old.Source == pr.Source {
+			continue
+		}
+		headRepo := old.Head.Repo.FullName
+		if headRepo == "" {
+			headRepo = fullName
+		}
+		_, err = scmClient.Git.DeleteRef(ctx, headRepo, "heads/"+old.Source)
+		if err != nil {
+			g.log.Warnf("failed to delete branch %s of superseded Pull Request %s: %s", old.Source, old.Link, err.Error())
+		}
+	}
+	return nil
+}
+
+// pullRequestVersion returns the version of the application the Pull Request promotes from its title such as
+// 'chore: promote myapp to version 1.2.3' or else the first semantic version in its title or branch name. Returns nil
+// if there is none
+func pullRequestVersion(pr *scm.PullRequest, app string) *semver.Version {
+	var words []string
+	for _, word := range strings.Fields(pr.Title) {
+		words = append(words, strings.Trim(word, ",;:()[]"))
+	}
+	for i := 0; i+3 < len(words); i++ {
+		if words[i] == app && words[i+1] == "to" && words[i+2] == "version" {
+			if sv, err := semver.NewVersion(words[i+3]); err == nil {
+				return sv
+			}
+		}
+	}
+	for _, part := range strings.Split(pr.Source, "/") {
+		// the version may follow the application name in the branch name such as 'promote-myapp-1.2.3'
+		for i, c := range part {
+			if c == '-' {
+				words = append(words, part[i+1:])
+			}
+		}
+		words = append(words, part)
+	}
+	for _, word := range words {
+		if sv, err := semver.NewVersion(word); err == nil {
+			return sv
+		}
+	}
+	return nil
+}
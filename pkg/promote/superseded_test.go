@@ -0,0 +1,61 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseSupersededPullRequests(t *testing.T) {
+	scmClient, fakeData := fake.NewDefault()
+	o := &promote.Options{}
+	o.ScmClient = scmClient
+
+	repo := scm.Repository{Namespace: "myorg", Name: "environment-production", FullName: "myorg/environment-production"}
+	newPR := func(number int, title, source string, labels ...string) *scm.PullRequest {
+		pr := &scm.PullRequest{
+			Number: number,
+			Title:  title,
+			Source: source,
+			Link:   "https://github.com/myorg/environment-production/pull/" + source,
+			Base:   scm.PullRequestBranch{Repo: repo},
+		}
+		for _, l := range labels {
+			pr.Labels = append(pr.Labels, &scm.Label{Name: l})
+		}
+		fakeData.PullRequests[number] = pr
+		return pr
+	}
+	labels := []string{"env/production", "dependency/myorg/myapp"}
+	newPR(1, "chore: promote myapp to version 1.2.3", "promote-myapp-1.2.3", "env/production", "dependency/myorg/myapp", "do-not-merge/hold")
+	newPR(2, "chore: promote otherapp to version 2.0.0", "promote-otherapp-2.0.0", "env/production", "dependency/myorg/otherapp", "do-not-merge/hold")
+	newPR(3, "chore: promote myapp to version 1.2.3", "promote-myapp-staging", "env/staging", "dependency/myorg/myapp")
+	newPR(5, "chore: promote myapp to version 1.10.0", "promote-myapp-1.10.0", "env/production", "dependency/myorg/myapp", "do-not-merge/hold")
+	newPR(6, "promote the latest myapp", "promote/production/myapp", "env/production", "dependency/myorg/myapp", "do-not-merge/hold")
+	newPR(7, "release myapp", "promote/production/myapp-v1.2.0", "env/production", "dependency/myorg/myapp", "do-not-merge/hold")
+	pr := newPR(4, "chore: promote myapp to version 1.2.4", "promote-myapp-1.2.4", "env/production", "dependency/myorg/myapp", "do-not-merge/hold")
+
+	o.Application = "myapp"
+	err := o.NewGroupContext(nil, "1.2.4").CloseSupersededPullRequests(pr, labels)
+	require.NoError(t, err, "failed to close superseded Pull Requests")
+
+	assert.True(t, fakeData.PullRequests[1].Closed, "the Pull Request of the previous version should be closed")
+	assert.False(t, fakeData.PullRequests[2].Closed, "the Pull Request of another app should not be closed")
+	assert.False(t, fakeData.PullRequests[3].Closed, "the Pull Request of another environment should not be closed")
+	assert.False(t, fakeData.PullRequests[4].Closed, "the new Pull Request should not be closed")
+	assert.False(t, fakeData.PullRequests[5].Closed, "the Pull Request of a newer version should not be closed")
+	assert.False(t, fakeData.PullRequests[6].Closed, "the Pull Request of an unknown version should not be closed")
+	assert.True(t, fakeData.PullRequests[7].Closed, "the Pull Request of the version in the branch name should be closed")
+
+	require.Len(t, fakeData.PullRequestComments[1], 1, "should have commented on the superseded Pull Request")
+	assert.Contains(t, fakeData.PullRequestComments[1][0].Body, pr.Link, "comment should link to the new Pull Request")
+	require.Len(t, fakeData.RefsDeleted, 2, "should have deleted the branches of the superseded Pull Requests")
+	assert.ElementsMatch(t, []string{"heads/promote-myapp-1.2.3", "heads/promote/production/myapp-v1.2.0"}, []string{fakeData.RefsDeleted[0].Ref, fakeData.RefsDeleted[1].Ref}, "deleted branches")
+}
@@ -0,0 +1,171 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x/go-scm/scm"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/activities"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TemplateData the data available to the go templates of the promotion
+type TemplateData struct {
+	// Application the name of the application being promoted
+	Application string
+
+	// Version the version being promoted
+	Version string
+
+	// PreviousVersion the version of the application last promoted to the first environment if known
+	PreviousVersion string
+
+	// Apps the applications being promoted
+	Apps []App
+
+	// Environment the key of the first environment being promoted to
+	Environment string
+
+	// Environments the keys of all the environments being promoted to
+	Environments []string
+
+	// PullRequestNumber the number of the Pull Request if it has been created
+	PullRequestNumber int
+
+	// PullRequestTitle the title of the Pull Request if it has been created
+	PullRequestTitle string
+
+	// PullRequestURL the URL of the Pull Request if it has been created
+	PullRequestURL string
+
+	// Changelog the changelog of the applications
+	Changelog string
+
+	// BuildURL the URL of the pipeline promoting the applications
+	BuildURL string
+
+	// ReleaseNotesURL the URL of the release notes of the application
+	ReleaseNotesURL string
+}
+
+// templateData returns the data of the templates of promoting to the environments via the Pull Request
+func (g *GroupContext) templateData(envs []*jxcore.EnvironmentConfig, pr *scm.PullRequest) *TemplateData {
+	data := &TemplateData{
+		Application: g.Application,
+		Version:     g.Version,
+		Apps:        g.PromoteApps(),
+	}
+	for _, env := range envs {
+		if data.Environment == "" {
+			data.Environment = env.Key
+		}
+		data.Environments = append(data.Environments, env.Key)
+	}
+	if pr != nil {
+		data.PullRequestNumber = pr.Number
+		data.PullRequestTitle = pr.Title
+		data.PullRequestURL = pr.Link
+	}
+	return data
+}
+
+// RenderPullRequestTemplates evaluates the title, body and commit message templates of the Pull Request promoting to
+// the environments. The notes of any change freeze or approval are added after the body
+func (g *GroupContext) RenderPullRequestTemplates(envs []*jxcore.EnvironmentConfig, templates *v1alpha1.PullRequestTemplate, changelog, notes string) error {
+	data := g.templateData(envs, nil)
+	data.Changelog = changelog
+	if len(envs) > 0 {
+		promoteKey := g.CreatePromoteKey(envs[0])
+		data.BuildURL = promoteKey.BuildURL
+		data.ReleaseNotesURL = promoteKey.ReleaseNotesURL
+		data.PreviousVersion = g.previousVersion(envs[0], promoteKey)
+	}
+
+	if templates.Title != "" {
+		title, err := evaluateTemplate("pullRequest.title", templates.Title, data)
+		if err != nil {
+			return err
+		}
+		title = strings.TrimSpace(title)
+		if title != "" {
+			g.PullRequest.CommitTitle = title
+		}
+	}
+	data.PullRequestTitle = g.PullRequest.CommitTitle
+
+	if templates.Body != "" {
+		body, err := evaluateTemplate("pullRequest.body", templates.Body, data)
+		if err != nil {
+			return err
+		}
+		g.PullRequest.CommitMessage = strings.TrimSpace(body) + notes
+		if strings.Contains(templates.Body, ".Changelog") {
+			// the body already includes the changelog
+			g.PullRequest.CommitChangelog = ""
+		}
+	}
+
+	if templates.CommitMessage != "" {
+		message, err := evaluateTemplate("pullRequest.commitMessage", templates.CommitMessage, data)
+		if err != nil {
+			return err
+		}
+		g.PullRequest.FullCommitMessage = strings.TrimSpace(message)
+	}
+	return nil
+}
+
+// previousVersion returns the version of the application in the most recent PipelineActivity of the pipeline which
+// merged a promotion to the environment or an empty string if there is none
+func (o *Options) previousVersion(env *jxcore.EnvironmentConfig, promoteKey *activities.PromoteStepActivityKey) string {
+	jxClient := o.JXClient
+	if jxClient == nil || promoteKey.Pipeline == "" {
+		return ""
+	}
+	list, err := jxClient.JenkinsV1().PipelineActivities(o.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Logger().Debugf("failed to list PipelineActivities to find the previous version: %s", err.Error())
+		return ""
+	}
+	answer := ""
+	build := 0
+	for i := range list.Items {
+		a := &list.Items[i]
+		if a.Spec.Pipeline != promoteKey.Pipeline || a.Name == promoteKey.Name || a.Spec.Version == "" {
+			continue
+		}
+		n, err := strconv.Atoi(a.Spec.Build)
+		if err != nil || n <= build {
+			continue
+		}
+		for j := range a.Spec.Steps {
+			ps := a.Spec.Steps[j].Promote
+			if ps != nil && ps.Environment == env.Key && ps.PullRequest != nil && ps.PullRequest.MergeCommitSHA != "" {
+				answer = a.Spec.Version
+				build = n
+				break
+			}
+		}
+	}
+	return answer
+}
+
+// evaluateTemplate evaluates the named go template with the data
+func evaluateTemplate(name, templateText string, data *TemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s go template: %s: %w", name, templateText, err)
+	}
+	buf := &strings.Builder{}
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return buf.String(), fmt.Errorf("failed to evaluate %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,82 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	v1fake "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderPullRequestTemplates(t *testing.T) {
+	t.Setenv("BUILD_URL", "https://dashboard.example.com/myorg/myapp/master/3")
+
+	activity := func(build, version, env, mergeSHA string) *v1.PipelineActivity {
+		return &v1.PipelineActivity{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "myorg-myapp-master-" + build,
+				Namespace: "jx",
+			},
+			Spec: v1.PipelineActivitySpec{
+				Pipeline: "myorg/myapp/master",
+				Build:    build,
+				Version:  version,
+				Steps: []v1.PipelineActivityStep{
+					{
+						Kind: v1.ActivityStepKindTypePromote,
+						Promote: &v1.PromoteActivityStep{
+							Environment: env,
+							PullRequest: &v1.PromotePullRequestStep{MergeCommitSHA: mergeSHA},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	o := &promote.Options{
+		Namespace:        "jx",
+		Pipeline:         "myorg/myapp/master",
+		Build:            "3",
+		Version:          "1.2.3",
+		IgnoreLocalFiles: true,
+	}
+	o.Application = "myapp"
+	o.JXClient = v1fake.NewSimpleClientset(
+		activity("1", "1.2.1", "production", "abc123"),
+		activity("2", "1.2.2", "production", ""),
+		activity("4", "1.2.4", "staging", "def456"),
+		activity("3", "1.2.3", "production", ""),
+	)
+
+	production := []*jxcore.EnvironmentConfig{{Key: "production"}}
+	g := o.NewGroupContext(production, o.Version)
+	g.PullRequest.CommitTitle = "chore: promote myapp to version 1.2.3"
+	g.PullRequest.CommitChangelog = "* fix: a bug"
+
+	err := g.RenderPullRequestTemplates(production, &v1alpha1.PullRequestTemplate{
+		Title:         "release: {{ .Application }} {{ .PreviousVersion }} -> {{ .Version }} in {{ .Environment }}",
+		Body:          "Built by {{ .BuildURL }}\n\n{{ .Changelog }}",
+		CommitMessage: "{{ .PullRequestTitle }}\n\nPromoted from {{ .PreviousVersion }}",
+	}, "* fix: a bug", "\n\nThis Pull Request requires 1 approval before it is merged")
+	require.NoError(t, err, "failed to render the Pull Request templates")
+
+	assert.Equal(t, "release: myapp 1.2.1 -> 1.2.3 in production", g.PullRequest.CommitTitle, "title")
+	assert.Equal(t, "Built by https://dashboard.example.com/myorg/myapp/master/3\n\n* fix: a bug\n\nThis Pull Request requires 1 approval before it is merged", g.PullRequest.CommitMessage, "body")
+	assert.Empty(t, g.PullRequest.CommitChangelog, "the changelog should not be added twice")
+	assert.Equal(t, "release: myapp 1.2.1 -> 1.2.3 in production\n\nPromoted from 1.2.1", g.PullRequest.FullCommitMessage, "commit message")
+
+	err = g.RenderPullRequestTemplates(production, &v1alpha1.PullRequestTemplate{
+		Title: "{{ .Chart }}",
+	}, "", "")
+	require.Error(t, err, "should fail with an unknown template field")
+	assert.Contains(t, err.Error(), "pullRequest.title", "error")
+}
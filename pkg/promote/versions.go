@@ -0,0 +1,216 @@
+package promote
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promoteconfig"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// VersionPolicy specifies which versions of an application can be promoted
+type VersionPolicy struct {
+	// Constraints the semantic version constraints a version must satisfy such as '~1.4' or '>= 1.2, < 2'
+	Constraints []string
+
+	// AllowPrerelease whether pre-release versions can be promoted. If not specified pre-releases are
+	// ignored when selecting the latest version and are only rejected by a constraint
+	AllowPrerelease *bool
+}
+
+// VersionPolicy returns the version policy for the given environments combined with the command line options
+func (o *Options) VersionPolicy(envs ...*jxcore.EnvironmentConfig) *VersionPolicy {
+	policy := &VersionPolicy{}
+	if o.VersionConstraint != "" {
+		policy.Constraints = append(policy.Constraints, o.VersionConstraint)
+	}
+	if o.AllowPrerelease {
+		allow := true
+		policy.AllowPrerelease = &allow
+	}
+	for _, env := range envs {
+		envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+		if envSpec == nil {
+			continue
+		}
+		if envSpec.VersionConstraint != "" {
+			policy.Constraints = append(policy.Constraints, envSpec.VersionConstraint)
+		}
+		if envSpec.AllowPrerelease != nil && (policy.AllowPrerelease == nil || !*envSpec.AllowPrerelease) {
+			allow := *envSpec.AllowPrerelease
+			policy.AllowPrerelease = &allow
+		}
+	}
+	return policy
+}
+
+// hasEnvironmentVersionPolicy returns true if any of the environments have their own version policy
+func (o *Options) hasEnvironmentVersionPolicy(envs []*jxcore.EnvironmentConfig) bool {
+	for _, env := range envs {
+		envSpec := promoteconfig.FindEnvironment(o.PromoteConfig, env.Key)
+		if envSpec != nil && (envSpec.VersionConstraint != "" || envSpec.AllowPrerelease != nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty returns true if the policy does not restrict versions
+func (p *VersionPolicy) IsEmpty() bool {
+	return len(p.Constraints) == 0 && p.AllowPrerelease == nil
+}
+
+func (p *VersionPolicy) allowPrerelease() bool {
+	return p.AllowPrerelease != nil && *p.AllowPrerelease
+}
+
+func (p *VersionPolicy) String() string {
+	texts := append([]string{}, p.Constraints...)
+	if p.AllowPrerelease != nil {
+		if *p.AllowPrerelease {
+			texts = append(texts, "allowing pre-releases")
+		} else {
+			texts = append(texts, "excluding pre-releases")
+		}
+	}
+	return strings.Join(texts, " and ")
+}
+
+func (p *VersionPolicy) constraints() ([]*semver.Constraints, error) {
+	var answer []*semver.Constraints
+	for _, text := range p.Constraints {
+		c, err := semver.NewConstraint(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %s: %w", text, err)
+		}
+		c.IncludePrerelease = p.allowPrerelease()
+		answer = append(answer, c)
+	}
+	return answer, nil
+}
+
+// Validate returns an error if the given version cannot be promoted with this policy
+func (p *VersionPolicy) Validate(version string) error {
+	if p.IsEmpty() {
+		return nil
+	}
+	constraints, err := p.constraints()
+	if err != nil {
+		return err
+	}
+	sv, err := semver.NewVersion(version)
+	if err != nil {
+		return fmt.Errorf("version %s is not a semantic version so cannot be checked against %s", version, p.String())
+	}
+	if sv.Prerelease() != "" && p.AllowPrerelease != nil && !*p.AllowPrerelease {
+		return fmt.Errorf("version %s is a pre-release", version)
+	}
+	for _, c := range constraints {
+		ok, errs := c.Validate(sv)
+		if !ok {
+			var messages []string
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			return fmt.Errorf("version %s does not satisfy the constraint %s: %s", version, c.String(), strings.Join(messages, ", "))
+		}
+	}
+	return nil
+}
+
+// SelectLatest returns the highest of the given versions which satisfies this policy. Versions which are not semantic
+// versions are ignored
+func (p *VersionPolicy) SelectLatest(versions []string) (string, error) {
+	constraints, err := p.constraints()
+	if err != nil {
+		return "", err
+	}
+	var maxSemVer *semver.Version
+	var invalid []string
+Versions:
+	for _, version := range versions {
+		sv, err := semver.NewVersion(version)
+		if err != nil {
+			log.Logger().Warnf("ignoring version %s as it is not a semantic version: %s", version, err.Error())
+			invalid = append(invalid, version)
+			continue
+		}
+		if sv.Prerelease() != "" && !p.allowPrerelease() {
+			continue
+		}
+		for _, c := range constraints {
+			if !c.Check(sv) {
+				continue Versions
+			}
+		}
+		if maxSemVer == nil || sv.GreaterThan(maxSemVer) {
+			maxSemVer = sv
+		}
+	}
+	switch {
+	case maxSemVer != nil:
+		return maxSemVer.Original(), nil
+	case !p.IsEmpty():
+		return "", fmt.Errorf("no version matches %s", p.String())
+	case len(invalid) > 0:
+		return "", fmt.Errorf("no semantic versions found in %s", strings.Join(invalid, ", "))
+	default:
+		return "", fmt.Errorf("no versions found")
+	}
+}
+
+// ResolveEnvironmentsVersion returns the version to promote to the given environments.
+//
+// If the version was defaulted to the latest version in the helm repositories then the latest version which satisfies
+// the version policy of the environments is used. Otherwise the version is validated against the policy
+func (o *Options) ResolveEnvironmentsVersion(envs []*jxcore.EnvironmentConfig, version string) (string, error) {
+	if !o.hasEnvironmentVersionPolicy(envs) {
+		return version, nil
+	}
+	policy := o.VersionPolicy(envs...)
+	if len(o.Apps) > 0 {
+		for i := range o.Apps {
+			app := &o.Apps[i]
+			err := policy.Validate(app.Version)
+			if err != nil {
+				return version, fmt.Errorf("cannot promote app %s: %w", app.Name, err)
+			}
+		}
+		return version, nil
+	}
+	if o.latestVersion {
+		answer, err := o.findLatestVersionWithPolicy(o.Application, policy)
+		if err != nil {
+			return version, fmt.Errorf("failed to find latest version of app %s: %w", o.Application, err)
+		}
+		return answer, nil
+	}
+	err := policy.Validate(version)
+	if err != nil {
+		return version, fmt.Errorf("cannot promote app %s: %w", o.Application, err)
+	}
+	return version, nil
+}
+
+func (o *Options) findLatestVersion(app string) (string, error) {
+	return o.findLatestVersionWithPolicy(app, o.VersionPolicy())
+}
+
+func (o *Options) findLatestVersionWithPolicy(app string, policy *VersionPolicy) (string, error) {
+	charts, err := o.Helm().SearchCharts(app, true)
+	if err != nil {
+		return "", err
+	}
+
+	var versions []string
+	for _, chart := range charts {
+		versions = append(versions, chart.ChartVersion)
+	}
+	answer, err := policy.SelectLatest(versions)
+	if err != nil {
+		return "", fmt.Errorf("could not find a version of app %s in the helm repositories: %w", app, err)
+	}
+	return answer, nil
+}
@@ -0,0 +1,108 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionPolicySelectLatest(t *testing.T) {
+	allow := true
+	versions := []string{"1.3.9", "1.4.0", "1.4.2", "1.5.0-rc.1", "1.4.3-beta.1", "1.4.1", "2.0.0"}
+
+	testCases := []struct {
+		name     string
+		policy   promote.VersionPolicy
+		expected string
+	}{
+		{
+			name:     "latest",
+			expected: "2.0.0",
+		},
+		{
+			name:     "latest-prerelease",
+			policy:   promote.VersionPolicy{AllowPrerelease: &allow},
+			expected: "2.0.0",
+		},
+		{
+			name:     "patch",
+			policy:   promote.VersionPolicy{Constraints: []string{"~1.4"}},
+			expected: "1.4.2",
+		},
+		{
+			name:     "patch-prerelease",
+			policy:   promote.VersionPolicy{Constraints: []string{"~1.4"}, AllowPrerelease: &allow},
+			expected: "1.4.3-beta.1",
+		},
+		{
+			name:     "minor-prerelease",
+			policy:   promote.VersionPolicy{Constraints: []string{"^1"}, AllowPrerelease: &allow},
+			expected: "1.5.0-rc.1",
+		},
+		{
+			name:     "combined",
+			policy:   promote.VersionPolicy{Constraints: []string{"^1", "< 1.4.2"}},
+			expected: "1.4.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		actual, err := tc.policy.SelectLatest(versions)
+		require.NoError(t, err, "for %s", tc.name)
+		assert.Equal(t, tc.expected, actual, "for %s", tc.name)
+	}
+
+	policy := promote.VersionPolicy{Constraints: []string{"~3.0"}}
+	_, err := policy.SelectLatest(versions)
+	require.Error(t, err, "should not find a version")
+
+	policy = promote.VersionPolicy{}
+	actual, err := policy.SelectLatest([]string{"v1.9.0", "latest", "v1.10.0", "1.2.3"})
+	require.NoError(t, err, "should find a version with a v prefix")
+	assert.Equal(t, "v1.10.0", actual, "should compare versions semantically and ignore invalid versions")
+
+	_, err = policy.SelectLatest([]string{"latest", "nightly"})
+	require.Error(t, err, "should not select a version which is not a semantic version")
+	assert.Contains(t, err.Error(), "no semantic versions found in latest, nightly", "error")
+}
+
+func TestResolveEnvironmentsVersion(t *testing.T) {
+	stable := false
+	o := &promote.Options{
+		PromoteConfig: &v1alpha1.Promote{
+			Spec: v1alpha1.PromoteSpec{
+				Environments: []v1alpha1.EnvironmentSpec{
+					{
+						Key:               "production",
+						VersionConstraint: "~1.4",
+						AllowPrerelease:   &stable,
+					},
+				},
+			},
+		},
+	}
+	o.Application = "myapp"
+	staging := &jxcore.EnvironmentConfig{Key: "staging"}
+	production := &jxcore.EnvironmentConfig{Key: "production"}
+
+	version, err := o.ResolveEnvironmentsVersion([]*jxcore.EnvironmentConfig{staging}, "1.5.0-rc.1")
+	require.NoError(t, err, "staging has no version policy")
+	assert.Equal(t, "1.5.0-rc.1", version)
+
+	version, err = o.ResolveEnvironmentsVersion([]*jxcore.EnvironmentConfig{production}, "1.4.7")
+	require.NoError(t, err, "production accepts patch releases")
+	assert.Equal(t, "1.4.7", version)
+
+	for _, v := range []string{"1.5.0", "1.4.8-rc.1"} {
+		_, err = o.ResolveEnvironmentsVersion([]*jxcore.EnvironmentConfig{production}, v)
+		require.Error(t, err, "production should not accept version %s", v)
+		t.Logf("got expected error: %s", err.Error())
+	}
+}
@@ -0,0 +1,239 @@
+package promote
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	optionWebhookListen   = "webhook-listen"
+	optionWebhookPollTime = "webhook-poll-time"
+	optionWebhookSecret   = "webhook-secret"
+
+	// EnvWebhookSecret the environment variable of the HMAC token used to validate webhooks if none is specified
+	EnvWebhookSecret = "HMAC_TOKEN"
+
+	// DefaultWebhookPollTime the default time after which a Pull Request is polled if no webhooks of its repository
+	// have been received
+	DefaultWebhookPollTime = 5 * time.Minute
+
+	// minRateLimitRemaining the number of requests left in the rate limit of the git provider below which polling
+	// waits for the rate limit to reset
+	minRateLimitRemaining = 10
+)
+
+// WebhookListener receives the webhooks of the git provider so that Pull Requests are checked when a pull_request,
+// status or other event of their repository is received rather than at a fixed interval
+type WebhookListener struct {
+	// Secret the HMAC token used to validate the webhooks. If empty the webhooks are not validated
+	Secret string
+
+	lock        sync.Mutex
+	scmClient   *scm.Client
+	server      *http.Server
+	addr        string
+	subscribers map[string][]chan struct{}
+}
+
+// NewWebhookListener creates a listener validating webhooks with the secret
+func NewWebhookListener(secret string) *WebhookListener {
+	return &WebhookListener{
+		Secret:      secret,
+		subscribers: map[string][]chan struct{}{},
+	}
+}
+
+// Start starts listening on the address for webhooks parsed by the ScmClient if it is not already listening. The
+// webhooks of a single git server are received so an error is returned if the listener has already started for a
+// different git server and the Pull Requests of its repositories should be polled instead
+func (l *WebhookListener) Start(addr string, scmClient *scm.Client) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.server != nil {
+		if !sameGitServer(l.scmClient, scmClient) {
+			return fmt.Errorf("already receiving the webhooks of %s", gitServerName(l.scmClient))
+		}
+		return nil
+	}
+	l.scmClient = scmClient
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for webhooks on %s: %w", addr, err)
+	}
+	l.addr = listener.Addr().String()
+	server := &http.Server{
+		Handler:           l,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	l.server = server
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Logger().Warnf("failed to serve webhooks on %s: %s", addr, err.Error())
+		}
+	}()
+	log.Logger().Infof("listening for webhooks on %s", termcolor.ColorInfo(l.addr))
+	return nil
+}
+
+// Addr returns the address the listener is listening on or an empty string if it has not started
+func (l *WebhookListener) Addr() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.addr
+}
+
+// Close stops listening for webhooks
+func (l *WebhookListener) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.server == nil {
+		return nil
+	}
+	err := l.server.Close()
+	l.server = nil
+	l.scmClient = nil
+	l.addr = ""
+	return err
+}
+
+// sameGitServer returns true if the clients use the same driver and URL of the git server
+func sameGitServer(c1, c2 *scm.Client) bool {
+	return c1.Driver == c2.Driver && gitServerName(c1) == gitServerName(c2)
+}
+
+// gitServerName returns the URL of the git server of the client or the name of its driver if it has no URL
+func gitServerName(scmClient *scm.Client) string {
+	if scmClient.BaseURL != nil {
+		return scmClient.BaseURL.String()
+	}
+	return scmClient.Driver.String()
+}
+
+// Subscribe returns a channel which receives a value when a webhook of the repository is received and the function
+// to unsubscribe
+func (l *WebhookListener) Subscribe(fullName string) (<-chan struct{}, func()) {
+	key := strings.ToLower(fullName)
+	ch := make(chan struct{}, 1)
+
+	l.lock.Lock()
+	l.subscribers[key] = append(l.subscribers[key], ch)
+	l.lock.Unlock()
+
+	unsubscribe := func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		var remaining []chan struct{}
+		for _, c := range l.subscribers[key] {
+			if c != ch {
+				remaining = append(remaining, c)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(l.subscribers, key)
+		} else {
+			l.subscribers[key] = remaining
+		}
+	}
+	return ch, unsubscribe
+}
+
+// ServeHTTP parses the webhook and notifies the subscribers of its repository
+func (l *WebhookListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.lock.Lock()
+	scmClient := l.scmClient
+	l.lock.Unlock()
+	if scmClient == nil || scmClient.Webhooks == nil {
+		http.Error(w, "not ready to receive webhooks", http.StatusServiceUnavailable)
+		return
+	}
+
+	hook, err := scmClient.Webhooks.Parse(r, func(scm.Webhook) (string, error) {
+		return l.Secret, nil
+	})
+	if err != nil {
+		switch {
+		case scm.IsUnknownWebhook(err):
+			w.WriteHeader(http.StatusOK)
+		case errors.Is(err, scm.ErrSignatureInvalid):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			log.Logger().Debugf("failed to parse webhook: %s", err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	fullName := hook.Repository().FullName
+	log.Logger().Debugf("received %s webhook for repository %s", string(hook.Kind()), fullName)
+	l.notify(fullName)
+	w.WriteHeader(http.StatusOK)
+}
+
+// notify notifies the subscribers of the repository without blocking
+func (l *WebhookListener) notify(fullName string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, ch := range l.subscribers[strings.ToLower(fullName)] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// RateLimitDelay returns how long to wait for the rate limit of the git provider to reset if there are too few
+// requests remaining
+func RateLimitDelay(rate scm.Rate, now time.Time) time.Duration {
+	if rate.Limit <= 0 || rate.Reset <= 0 || rate.Remaining >= minRateLimitRemaining {
+		return 0
+	}
+	delay := time.Unix(rate.Reset, 0).Sub(now)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// waitForNextPoll waits until the Pull Request should be polled again. When receiving webhooks this is when a webhook
+// of its repository is received or after the --webhook-poll-time otherwise after the --pull-request-poll-time. If the
+// rate limit of the git provider is exhausted it waits for the rate limit to reset but never past the timeout
+func (g *GroupContext) waitForNextPoll(events <-chan struct{}, end time.Time) {
+	delay := *g.PullRequestPollDuration
+	if events != nil {
+		delay = g.WebhookPollDuration
+		if delay <= 0 {
+			delay = DefaultWebhookPollTime
+		}
+		if remaining := time.Until(end); remaining > 0 && remaining < delay {
+			delay = remaining
+		}
+	}
+	if g.PullRequest.ScmClient != nil {
+		limited := RateLimitDelay(g.PullRequest.ScmClient.Rate(), time.Now())
+		if remaining := time.Until(end); remaining > 0 && remaining < limited {
+			limited = remaining
+		}
+		if limited > delay {
+			g.log.Infof("waiting %s for the rate limit of the git provider to reset", limited.Round(time.Second).String())
+			delay = limited
+			events = nil
+		}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-events:
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,91 @@
+//go:build unit
+// +build unit
+
+package promote_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/promote"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	"github.com/jenkins-x/go-scm/scm/driver/gitlab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookListener(t *testing.T) {
+	scmClient, err := github.New("https://api.github.com")
+	require.NoError(t, err, "failed to create the github client")
+
+	secret := "my-hmac-token"
+	listener := promote.NewWebhookListener(secret)
+	err = listener.Start("127.0.0.1:0", scmClient)
+	require.NoError(t, err, "failed to start the listener")
+	defer listener.Close()
+	assert.NotEmpty(t, listener.Addr(), "address")
+
+	sameServer, err := github.New("https://api.github.com")
+	require.NoError(t, err, "failed to create the github client")
+	err = listener.Start("127.0.0.1:0", sameServer)
+	require.NoError(t, err, "should receive the webhooks of the same git server")
+	otherServer, err := gitlab.New("https://gitlab.com")
+	require.NoError(t, err, "failed to create the gitlab client")
+	err = listener.Start("127.0.0.1:0", otherServer)
+	require.Error(t, err, "should not receive the webhooks of another git server")
+
+	production, unsubscribe := listener.Subscribe("myorg/environment-production")
+	defer unsubscribe()
+	staging, unsubscribeStaging := listener.Subscribe("myorg/environment-staging")
+	unsubscribeStaging()
+
+	webhook := func(payload, signature string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+		r.Header.Set("X-GitHub-Event", "status")
+		r.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+		r.Header.Set("X-Hub-Signature", signature)
+		w := httptest.NewRecorder()
+		listener.ServeHTTP(w, r)
+		return w.Code
+	}
+	payload := `{"sha": "abc123", "state": "success", "context": "pr-build", "repository": {"name": "environment-production", "full_name": "myorg/environment-production", "owner": {"login": "myorg"}}}`
+	mac := hmac.New(sha1.New, []byte(secret))
+	_, _ = mac.Write([]byte(payload))
+	signature := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, http.StatusUnauthorized, webhook(payload, "sha1=0000"), "invalid signature")
+	select {
+	case <-production:
+		t.Fatal("should not be notified of a webhook with an invalid signature")
+	default:
+	}
+
+	assert.Equal(t, http.StatusOK, webhook(payload, signature), "valid webhook")
+	select {
+	case <-production:
+	case <-time.After(time.Second):
+		t.Fatal("should be notified of the webhook of the repository")
+	}
+	select {
+	case <-staging:
+		t.Fatal("should not be notified after unsubscribing")
+	default:
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	reset := now.Add(10 * time.Minute).Unix()
+
+	assert.Zero(t, promote.RateLimitDelay(scm.Rate{}, now), "no rate limit")
+	assert.Zero(t, promote.RateLimitDelay(scm.Rate{Limit: 5000, Remaining: 4000, Reset: reset}, now), "plenty remaining")
+	assert.Equal(t, 10*time.Minute, promote.RateLimitDelay(scm.Rate{Limit: 5000, Remaining: 2, Reset: reset}, now), "exhausted")
+	assert.Zero(t, promote.RateLimitDelay(scm.Rate{Limit: 5000, Remaining: 0, Reset: now.Add(-time.Minute).Unix()}, now), "already reset")
+}
@@ -1,11 +1,15 @@
 package promoteconfig
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 
 	"github.com/jenkins-x-plugins/jx-promote/pkg/apis/promote/v1alpha1"
+	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -62,6 +66,88 @@ func Discover(dir, promoteNamespace string) (*v1alpha1.Promote, string, error) {
 	return config, "", nil
 }
 
+// DiscoverRepository discovers the promote configuration of a git repository via the git provider without cloning it.
+//
+// The configuration is discovered in the same way as Discover from the root directory of the repository
+func DiscoverRepository(ctx context.Context, scmClient *scm.Client, repo, ref, promoteNamespace string) (*v1alpha1.Promote, string, error) {
+	entries, _, err := scmClient.Contents.List(ctx, repo, "", ref, &scm.ListOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list files in repository %s: %w", repo, err)
+	}
+	dirs := map[string]bool{}
+	for _, e := range entries {
+		if e.Type == "dir" {
+			dirs[e.Name] = true
+		}
+	}
+
+	relPath := ".jx/promote.yaml"
+	if dirs[".jx"] {
+		data, err := findRepositoryFile(ctx, scmClient, repo, relPath, ref)
+		if err != nil {
+			return nil, "", err
+		}
+		if data != nil {
+			config := &v1alpha1.Promote{}
+			err = yaml.Unmarshal(data, config)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to unmarshal YAML file %s in repository %s: %w", relPath, repo, err)
+			}
+			return config, relPath, nil
+		}
+	}
+
+	if dirs["env"] {
+		data, err := findRepositoryFile(ctx, scmClient, repo, "env/Chart.yaml", ref)
+		if err != nil {
+			return nil, "", err
+		}
+		if data != nil {
+			config := v1alpha1.Promote{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "generated",
+				},
+				Spec: v1alpha1.PromoteSpec{
+					HelmRule: &v1alpha1.HelmRule{
+						Path: "env",
+					},
+				},
+			}
+			return &config, "", nil
+		}
+	}
+
+	path := "helmfile.yaml"
+	if dirs["helmfiles"] && promoteNamespace != "" {
+		// lets assume we are using a nested helmfile
+		path = "helmfiles/" + promoteNamespace + "/helmfile.yaml"
+	}
+	config := &v1alpha1.Promote{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "generated",
+		},
+		Spec: v1alpha1.PromoteSpec{
+			HelmfileRule: &v1alpha1.HelmfileRule{
+				Path:      path,
+				Namespace: promoteNamespace,
+			},
+		},
+	}
+	return config, "", nil
+}
+
+// findRepositoryFile returns the contents of the file in the repository or nil if it does not exist
+func findRepositoryFile(ctx context.Context, scmClient *scm.Client, repo, path, ref string) ([]byte, error) {
+	content, res, err := scmClient.Contents.Find(ctx, repo, path, ref)
+	if err != nil {
+		if errors.Is(err, scm.ErrNotFound) || (res != nil && res.Status == http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find file %s in repository %s: %w", path, repo, err)
+	}
+	return content.Data, nil
+}
+
 func findHelmfile(dir, promoteNamespace string) (string, error) {
 	helmfilesDir := filepath.Join(dir, "helmfiles")
 	exists, err := files.DirExists(helmfilesDir)
@@ -121,3 +207,17 @@ func LoadPromoteFile(fileName string) (*v1alpha1.Promote, error) {
 
 	return config, nil
 }
+
+// FindEnvironment returns the promotion settings of the environment with the given key or nil if there are none
+func FindEnvironment(config *v1alpha1.Promote, key string) *v1alpha1.EnvironmentSpec {
+	if config == nil {
+		return nil
+	}
+	for i := range config.Spec.Environments {
+		env := &config.Spec.Environments[i]
+		if env.Key == key {
+			return env
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package scmretry
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// DefaultMaxRetries the default number of times a request is retried
+	DefaultMaxRetries = 5
+
+	// DefaultBaseDelay the default delay before the first retry of a transient error which is doubled for each retry
+	DefaultBaseDelay = time.Second
+
+	// DefaultMaxDelay the default maximum time to wait before retrying. If a rate limit resets later than this the
+	// rate limited response is returned rather than waiting
+	DefaultMaxDelay = 2 * time.Minute
+
+	// maxBodySize the maximum size of a rejected response body read to detect a secondary rate limit
+	maxBodySize = 64 * 1024
+)
+
+// Transport retries the requests of a git provider which were rejected by its rate limits or failed with a transient
+// server error. Rate limited requests are retried after the time given by the Retry-After or rate limit reset headers
+// and server errors of idempotent requests are retried with a jittered exponential back off
+type Transport struct {
+	// Base the transport used to make the requests. Defaults to http.DefaultTransport
+	Base http.RoundTripper
+
+	// MaxRetries the maximum number of times a request is retried. Defaults to DefaultMaxRetries
+	MaxRetries int
+
+	// BaseDelay the delay before the first retry of a server error. Defaults to DefaultBaseDelay
+	BaseDelay time.Duration
+
+	// MaxDelay the maximum time to wait before a retry. Defaults to DefaultMaxDelay
+	MaxDelay time.Duration
+}
+
+// Wrap makes the requests of the ScmClient retry rate limited and transient server errors if they do not already
+func Wrap(scmClient *scm.Client) *scm.Client {
+	if scmClient == nil {
+		return nil
+	}
+	if scmClient.Client == nil {
+		scmClient.Client = &http.Client{}
+	}
+	if _, ok := scmClient.Client.Transport.(*Transport); !ok {
+		scmClient.Client.Transport = &Transport{Base: scmClient.Client.Transport}
+	}
+	return scmClient
+}
+
+// RoundTrip makes the request retrying it if it is rate limited or fails with a transient server error
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	maxRetries := t.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := base.RoundTrip(req)
+		if err != nil {
+			return res, err
+		}
+		logQuota(req, res)
+		if attempt >= maxRetries || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return res, nil
+		}
+
+		delay, retry := t.retryDelay(req, res, attempt)
+		if !retry {
+			return res, nil
+		}
+		if delay > t.maxDelay() {
+			log.Logger().Debugf("not retrying %s %s as the git provider asked to wait %s", req.Method, req.URL.Path, delay.String())
+			return res, nil
+		}
+		log.Logger().Debugf("retrying %s %s in %s after status %d: attempt %d of %d", req.Method, req.URL.Path, delay.String(), res.StatusCode, attempt+1, maxRetries)
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxBodySize))
+		res.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying the request and whether it should be retried
+func (t *Transport) retryDelay(req *http.Request, res *http.Response, attempt int) (time.Duration, bool) {
+	if IsRateLimited(res) {
+		if delay, ok := RetryAfter(res.Header, time.Now()); ok {
+			return delay, true
+		}
+		return t.backOff(attempt), true
+	}
+	if !isTransient(res.StatusCode) || !isIdempotent(req.Method) {
+		return 0, false
+	}
+	if delay, ok := RetryAfter(res.Header, time.Now()); ok {
+		return delay, true
+	}
+	return t.backOff(attempt), true
+}
+
+// backOff returns the jittered exponential delay before the retry of the attempt
+func (t *Transport) backOff(attempt int) time.Duration {
+	delay := t.BaseDelay
+	if delay <= 0 {
+		delay = DefaultBaseDelay
+	}
+	delay <<= attempt
+	if delay <= 0 || delay > t.maxDelay() {
+		delay = t.maxDelay()
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)) //nolint:gosec
+}
+
+// maxDelay returns the maximum time to wait before a retry
+func (t *Transport) maxDelay() time.Duration {
+	if t.MaxDelay <= 0 {
+		return DefaultMaxDelay
+	}
+	return t.MaxDelay
+}
+
+// IsRateLimited returns true if the response rejected the request due to the primary or secondary rate limit of the
+// git provider
+func IsRateLimited(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		if res.Header.Get("Retry-After") != "" || rateLimitHeader(res.Header, "Remaining") == "0" {
+			return true
+		}
+		// GitHub only describes secondary rate limits in the body
+		data, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize))
+		res.Body.Close()
+		res.Body = io.NopCloser(bytes.NewReader(data))
+		if err != nil {
+			return false
+		}
+		return strings.Contains(strings.ToLower(string(data)), "rate limit")
+	default:
+		return false
+	}
+}
+
+// RetryAfter returns how long the git provider asked to wait from the Retry-After header or the time the rate limit
+// resets
+func RetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if t, err := http.ParseTime(value); err == nil {
+			return nonNegative(t.Sub(now)), true
+		}
+	}
+	if rateLimitHeader(header, "Remaining") == "0" {
+		if reset, err := strconv.ParseInt(rateLimitHeader(header, "Reset"), 10, 64); err == nil && reset > 0 {
+			return nonNegative(time.Unix(reset, 0).Sub(now)), true
+		}
+	}
+	return 0, false
+}
+
+// rateLimitHeader returns the rate limit header of GitHub or Gitea style X-RateLimit-* or GitLab style RateLimit-*
+func rateLimitHeader(header http.Header, name string) string {
+	value := header.Get("X-RateLimit-" + name)
+	if value == "" {
+		value = header.Get("RateLimit-" + name)
+	}
+	return value
+}
+
+// logQuota reports the remaining rate limit of the git provider in the debug logs
+func logQuota(req *http.Request, res *http.Response) {
+	remaining := rateLimitHeader(res.Header, "Remaining")
+	if remaining == "" {
+		return
+	}
+	log.Logger().Debugf("%s %s returned %d with %s of %s requests remaining", req.Method, req.URL.Path, res.StatusCode, remaining, rateLimitHeader(res.Header, "Limit"))
+}
+
+func isTransient(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isIdempotent returns true if the request can be repeated if the server failed part way through it. Requests such as
+// creating a Pull Request are not as the first request may have succeeded
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,153 @@
+//go:build unit
+// +build unit
+
+package scmretry_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/scmretry"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pullRequestJSON = `{"number": 1, "state": "open", "html_url": "https://github.com/myorg/environment-production/pull/1"}`
+
+func TestTransport(t *testing.T) {
+	var responses []func(w http.ResponseWriter)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4000")
+		if calls < len(responses) {
+			responses[calls](w)
+		} else {
+			_, _ = w.Write([]byte(pullRequestJSON))
+		}
+		calls++
+	}))
+	defer server.Close()
+
+	scmClient, err := github.New(server.URL)
+	require.NoError(t, err, "failed to create the github client")
+	scmretry.Wrap(scmClient)
+	scmretry.Wrap(scmClient)
+	transport, ok := scmClient.Client.Transport.(*scmretry.Transport)
+	require.True(t, ok, "the ScmClient should use the retrying transport")
+	_, ok = transport.Base.(*scmretry.Transport)
+	require.False(t, ok, "the transport should only be wrapped once")
+	transport.BaseDelay = time.Millisecond
+	transport.MaxDelay = time.Second
+
+	status := func(code int, body string, headers ...string) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			for i := 0; i+1 < len(headers); i += 2 {
+				w.Header().Set(headers[i], headers[i+1])
+			}
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(body))
+		}
+	}
+	ctx := context.Background()
+	testCases := []struct {
+		name      string
+		responses []func(w http.ResponseWriter)
+		create    bool
+		calls     int
+		fails     bool
+	}{
+		{
+			name:      "transient server errors",
+			responses: []func(w http.ResponseWriter){status(502, "bad gateway"), status(503, "unavailable")},
+			calls:     3,
+		},
+		{
+			name:      "secondary rate limit",
+			responses: []func(w http.ResponseWriter){status(403, `{"message": "You have exceeded a secondary rate limit"}`)},
+			calls:     2,
+		},
+		{
+			name:      "retry after",
+			responses: []func(w http.ResponseWriter){status(429, "slow down", "Retry-After", "0")},
+			calls:     2,
+		},
+		{
+			name:      "primary rate limit",
+			responses: []func(w http.ResponseWriter){status(403, `{"message": "API rate limit exceeded"}`, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))},
+			calls:     2,
+		},
+		{
+			name:      "rate limit resets too late",
+			responses: []func(w http.ResponseWriter){status(429, "slow down", "Retry-After", "3600")},
+			calls:     1,
+			fails:     true,
+		},
+		{
+			name:      "forbidden",
+			responses: []func(w http.ResponseWriter){status(403, `{"message": "Resource not accessible by integration"}`)},
+			calls:     1,
+			fails:     true,
+		},
+		{
+			name:      "pull requests are not created twice",
+			responses: []func(w http.ResponseWriter){status(502, "bad gateway")},
+			create:    true,
+			calls:     1,
+			fails:     true,
+		},
+		{
+			name:      "pull requests are created after the rate limit",
+			responses: []func(w http.ResponseWriter){status(429, "slow down", "Retry-After", "0")},
+			create:    true,
+			calls:     2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			responses = tc.responses
+			calls = 0
+			var pr *scm.PullRequest
+			if tc.create {
+				pr, _, err = scmClient.PullRequests.Create(ctx, "myorg/environment-production", &scm.PullRequestInput{Title: "chore: promote myapp to version 1.2.3", Head: "promote-myapp-1.2.3", Base: "main"})
+			} else {
+				pr, _, err = scmClient.PullRequests.Find(ctx, "myorg/environment-production", 1)
+			}
+			assert.Equal(t, tc.calls, calls, "requests")
+			if tc.fails {
+				require.Error(t, err, "should fail")
+				return
+			}
+			require.NoError(t, err, "should succeed after retrying")
+			assert.Equal(t, 1, pr.Number, "Pull Request number")
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	delay, ok := scmretry.RetryAfter(http.Header{"Retry-After": []string{"30"}}, now)
+	assert.True(t, ok, "seconds")
+	assert.Equal(t, 30*time.Second, delay, "seconds")
+
+	delay, ok = scmretry.RetryAfter(http.Header{"Retry-After": []string{now.Add(time.Minute).Format(http.TimeFormat)}}, now)
+	assert.True(t, ok, "date")
+	assert.Equal(t, time.Minute, delay, "date")
+
+	delay, ok = scmretry.RetryAfter(http.Header{
+		"Ratelimit-Remaining": []string{"0"},
+		"Ratelimit-Reset":     []string{strconv.FormatInt(now.Add(2*time.Minute).Unix(), 10)},
+	}, now)
+	assert.True(t, ok, "GitLab reset")
+	assert.Equal(t, 2*time.Minute, delay, "GitLab reset")
+
+	_, ok = scmretry.RetryAfter(http.Header{"X-Ratelimit-Remaining": []string{"10"}}, now)
+	assert.False(t, ok, "not rate limited")
+}
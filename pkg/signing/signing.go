@@ -0,0 +1,176 @@
+package signing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// FormatSSH signs commits with an SSH key
+	FormatSSH = "ssh"
+
+	// FormatOpenPGP signs commits with a GPG key
+	FormatOpenPGP = "openpgp"
+
+	// EnvSigningKey the environment variable of the path or contents of the key used to sign commits if none is
+	// specified
+	EnvSigningKey = "GIT_SIGNING_KEY"
+
+	// keyFileName the name of the file in the git directory of the clone the key is written to if it is not a file
+	keyFileName = "jx-promote-signing-key"
+)
+
+// Formats the supported formats of commit signatures
+var Formats = []string{FormatSSH, FormatOpenPGP}
+
+// ValidateFormat validates the format of the commit signatures
+func ValidateFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid signing format %s: expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// DefaultFormat returns the format of the key if no format is specified. If the key is a file the format is detected
+// from the contents of the file. An error is returned if the format cannot be detected, such as for a GPG key ID
+func DefaultFormat(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	content := key
+	if !isKeyContent(key) {
+		exists, err := files.FileExists(key)
+		if err != nil {
+			return "", fmt.Errorf("failed to check if signing key file %s exists: %w", key, err)
+		}
+		if exists {
+			data, err := os.ReadFile(key)
+			if err != nil {
+				return "", fmt.Errorf("failed to read signing key file %s: %w", key, err)
+			}
+			content = string(data)
+		}
+	}
+	switch {
+	case strings.Contains(content, "-----BEGIN PGP"):
+		return FormatOpenPGP, nil
+	case strings.Contains(content, "-----BEGIN"), strings.HasPrefix(content, "ssh-"), strings.HasPrefix(content, "key::"):
+		return FormatSSH, nil
+	default:
+		return "", fmt.Errorf("cannot detect the format of the signing key so the format must be specified: expected one of %s", strings.Join(Formats, ", "))
+	}
+}
+
+// Configure configures the git repository in the directory to sign all commits with the key, including those
+// regenerated when rebasing. The key is either the path of a key file or the contents of the key such as from an
+// environment variable. For the openpgp format the key can also be the ID of a key in the GPG keyring
+func Configure(gitter gitclient.Interface, runner cmdrunner.CommandRunner, dir, key, format string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil
+	}
+	var err error
+	if format == "" {
+		format, err = DefaultFormat(key)
+		if err != nil {
+			return err
+		}
+	}
+	err = ValidateFormat(format)
+	if err != nil {
+		return err
+	}
+	if runner == nil {
+		runner = cmdrunner.QuietCommandRunner
+	}
+
+	signingKey := key
+	if isKeyContent(key) {
+		signingKey, err = writeKeyFile(gitter, dir, key)
+		if err != nil {
+			return err
+		}
+	}
+	if format == FormatOpenPGP {
+		signingKey, err = importGPGKey(runner, signingKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	settings := [][]string{
+		{"gpg.format", format},
+		{"user.signingkey", signingKey},
+		{"commit.gpgsign", "true"},
+	}
+	for _, s := range settings {
+		_, err = gitter.Command(dir, "config", s[0], s[1])
+		if err != nil {
+			return fmt.Errorf("failed to set git config %s in dir %s: %w", s[0], dir, err)
+		}
+	}
+	log.Logger().Debugf("configured %s signing of commits in dir %s", format, dir)
+	return nil
+}
+
+// isKeyContent returns true if the key is the contents of a key rather than a file or key ID
+func isKeyContent(key string) bool {
+	return strings.Contains(key, "-----BEGIN")
+}
+
+// writeKeyFile writes the key to a file inside the git directory of the clone so that it is never committed
+func writeKeyFile(gitter gitclient.Interface, dir, key string) (string, error) {
+	gitDir, err := gitter.Command(dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the git directory of %s: %w", dir, err)
+	}
+	path := filepath.Join(strings.TrimSpace(gitDir), keyFileName)
+	err = os.WriteFile(path, []byte(key+"\n"), 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to save signing key file %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// importGPGKey imports the key file into the GPG keyring returning its fingerprint. If the key is not a file it is
+// assumed to be the ID of a key already in the keyring
+func importGPGKey(runner cmdrunner.CommandRunner, key string) (string, error) {
+	exists, err := files.FileExists(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if signing key file %s exists: %w", key, err)
+	}
+	if !exists {
+		return key, nil
+	}
+	_, err = runner(&cmdrunner.Command{
+		Name: "gpg",
+		Args: []string{"--batch", "--import", key},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to import GPG signing key: %w", err)
+	}
+	text, err := runner(&cmdrunner.Command{
+		Name: "gpg",
+		Args: []string{"--batch", "--with-colons", "--show-keys", key},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find the fingerprint of the GPG signing key: %w", err)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if fields[0] == "fpr" && len(fields) > 9 && fields[9] != "" {
+			return fields[9], nil
+		}
+	}
+	return "", fmt.Errorf("no fingerprint found for the GPG signing key in: %s", text)
+}
@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracerName the name of the tracer of the promotion spans
+	TracerName = "github.com/jenkins-x-plugins/jx-promote"
+
+	// ServiceName the default service name of the exported spans which can be overridden via $OTEL_SERVICE_NAME
+	ServiceName = "jx-promote"
+
+	// EnvEndpoint the environment variable of the base URL of the OTLP endpoint
+	EnvEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+	// EnvTracesEndpoint the environment variable of the URL of the OTLP traces endpoint
+	EnvTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+// Init exports the spans over OTLP if $OTEL_EXPORTER_OTLP_ENDPOINT or $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set.
+// It returns the context containing the parent span of $TRACEPARENT so that the promotion is correlated with the
+// pipeline and a function to flush the spans before exiting
+func Init(ctx context.Context) (context.Context, func(), error) {
+	propagator := propagation.TraceContext{}
+	otel.SetTextMapPropagator(propagator)
+	ctx = propagator.Extract(ctx, propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+		"tracestate":  os.Getenv("TRACESTATE"),
+	})
+
+	if !Enabled() {
+		return ctx, func() {}, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return ctx, func() {}, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return ctx, func() {}, fmt.Errorf("failed to create the OpenTelemetry resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	log.Logger().Debugf("exporting OpenTelemetry spans over OTLP")
+
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := tp.Shutdown(ctx)
+		if err != nil {
+			log.Logger().Warnf("failed to export the OpenTelemetry spans: %s", err.Error())
+		}
+	}
+	return ctx, shutdown, nil
+}
+
+// Enabled returns true if an OTLP endpoint is configured via $OTEL_EXPORTER_OTLP_ENDPOINT or
+// $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT
+func Enabled() bool {
+	return os.Getenv(EnvEndpoint) != "" || os.Getenv(EnvTracesEndpoint) != ""
+}
+
+// Start starts a span of the promotion
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span recording the error if there is one
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,93 @@
+//go:build unit
+// +build unit
+
+package tracing_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-promote/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTracing(t *testing.T) {
+	lock := sync.Mutex{}
+	var spans []*tracepb.Span
+	var services []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path, "path")
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"), "content type")
+		assert.Equal(t, "secret token", r.Header.Get("Authorization"), "authorization header")
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read body")
+		traces := &tracepb.TracesData{}
+		err = proto.Unmarshal(data, traces)
+		require.NoError(t, err, "failed to unmarshal body")
+
+		lock.Lock()
+		defer lock.Unlock()
+		for _, rs := range traces.ResourceSpans {
+			for _, kv := range rs.Resource.Attributes {
+				if kv.Key == "service.name" {
+					services = append(services, kv.Value.GetStringValue())
+				}
+			}
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID := "00f067aa0ba902b7"
+	t.Setenv("TRACEPARENT", "00-"+traceID+"-"+parentID+"-01")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL+"/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=secret%20token")
+
+	ctx, shutdown, err := tracing.Init(context.Background())
+	require.NoError(t, err, "failed to init tracing")
+
+	ctx, span := tracing.Start(ctx, "promote", attribute.String("promote.application", "myapp"))
+	_, child := tracing.Start(ctx, "clone")
+	tracing.End(child, errors.New("clone failed"))
+	tracing.End(span, nil)
+	shutdown()
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, spans, 2, "exported spans")
+	assert.Equal(t, []string{tracing.ServiceName}, services, "service names")
+
+	clone := spans[0]
+	promote := spans[1]
+	assert.Equal(t, "clone", clone.Name, "first span")
+	assert.Equal(t, "promote", promote.Name, "second span")
+	assert.Equal(t, traceID, hex.EncodeToString(promote.TraceId), "trace id should be propagated from $TRACEPARENT")
+	assert.Equal(t, parentID, hex.EncodeToString(promote.ParentSpanId), "parent span should be $TRACEPARENT")
+	assert.Equal(t, promote.SpanId, clone.ParentSpanId, "clone parent span")
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, clone.Status.GetCode(), "clone status")
+	assert.Equal(t, "clone failed", clone.Status.GetMessage(), "clone status message")
+	require.Len(t, promote.Attributes, 1, "promote attributes")
+	assert.Equal(t, "myapp", promote.Attributes[0].Value.GetStringValue(), "promote application attribute")
+}
+
+func TestTracingDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	assert.False(t, tracing.Enabled(), "tracing should be disabled without an endpoint")
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://collector:4318/custom")
+	assert.True(t, tracing.Enabled(), "tracing should be enabled with a traces endpoint")
+}